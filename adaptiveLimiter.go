@@ -0,0 +1,253 @@
+// Package util provides utility functions and types for common operations.
+package util
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// LimitSample describes one completed unit of work, as reported to a LimitAlgorithm.
+type LimitSample struct {
+	RTT      time.Duration // how long the work took
+	InFlight int           // work units in flight when this one started, including itself
+	Dropped  bool          // the work failed or timed out, a sign of overload
+}
+
+// LimitAlgorithm computes a new concurrency limit from the current limit and a completed sample.
+type LimitAlgorithm interface {
+	Update(limit float64, sample LimitSample) float64
+}
+
+// AIMD is an additive-increase/multiplicative-decrease LimitAlgorithm. The limit grows by Increase while the limiter
+// is at least half utilized and shrinks by the Backoff factor whenever a sample is dropped or slower than Timeout.
+type AIMD struct {
+	Increase float64       // additive increase per successful sample; defaults to 1
+	Backoff  float64       // multiplicative decrease factor in (0, 1); defaults to 0.9
+	Timeout  time.Duration // samples slower than this count as dropped; 0 disables
+}
+
+// Update implements LimitAlgorithm.
+func (a AIMD) Update(limit float64, s LimitSample) float64 {
+	if s.Dropped || (a.Timeout > 0 && s.RTT > a.Timeout) {
+		backoff := a.Backoff
+		if backoff <= 0 || backoff >= 1 {
+			backoff = 0.9
+		}
+		return limit * backoff
+	}
+	if float64(s.InFlight)*2 >= limit {
+		increase := a.Increase
+		if increase <= 0 {
+			increase = 1
+		}
+		return limit + increase
+	}
+	return limit
+}
+
+// Gradient is a latency-gradient LimitAlgorithm in the style of TCP Vegas. It compares the latest latency with a
+// slowly moving long-term average; when latency rises above the average the limit shrinks in proportion, otherwise it
+// grows by a queue allowance of sqrt(limit). Use NewGradient to construct one; a Gradient carries state and must not
+// be shared between limiters.
+type Gradient struct {
+	mu        sync.Mutex
+	longRTT   float64 // exponential moving average of RTT in nanoseconds
+	samples   int
+	window    int
+	tolerance float64
+	smoothing float64
+}
+
+// NewGradient creates a Gradient algorithm.
+//
+// Parameters:
+//   - window: The number of samples the long-term latency average spans (e.g. 600)
+//   - tolerance: How much latency may exceed the long-term average before the limit shrinks (e.g. 1.5)
+//   - smoothing: The weight in (0, 1] given to each new limit estimate (e.g. 0.2)
+//
+// Returns:
+//   - A pointer to a new Gradient instance
+//
+// Panics:
+//   - If window is less than 1, tolerance is less than 1, or smoothing is outside (0, 1]
+func NewGradient(window int, tolerance float64, smoothing float64) *Gradient {
+	if window < 1 {
+		panic("window must be greater than zero")
+	}
+	if tolerance < 1 {
+		panic("tolerance must be >= 1")
+	}
+	if smoothing <= 0 || smoothing > 1 {
+		panic("smoothing must be in (0, 1]")
+	}
+	return &Gradient{window: window, tolerance: tolerance, smoothing: smoothing}
+}
+
+// Update implements LimitAlgorithm.
+func (g *Gradient) Update(limit float64, s LimitSample) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	rtt := float64(s.RTT)
+	if g.samples < g.window {
+		g.samples++
+	}
+	if g.samples == 1 {
+		g.longRTT = rtt
+	} else {
+		g.longRTT += (rtt - g.longRTT) / float64(g.samples)
+	}
+
+	if s.Dropped {
+		return limit * 0.9
+	}
+	// don't grow the limit while it isn't being used
+	if float64(s.InFlight)*2 < limit {
+		return limit
+	}
+
+	gradient := 1.0
+	if rtt > 0 {
+		gradient = math.Max(0.5, math.Min(1, g.tolerance*g.longRTT/rtt))
+	}
+	estimate := limit*gradient + math.Sqrt(limit)
+	return limit*(1-g.smoothing) + estimate*g.smoothing
+}
+
+// AdaptiveLimiter bounds the number of concurrent work units and adjusts that bound with a LimitAlgorithm based on
+// the latency and failures of completed work. AdaptiveLimiter is safe for concurrent use.
+type AdaptiveLimiter struct {
+	mu       sync.Mutex
+	algo     LimitAlgorithm
+	limit    float64
+	minLimit float64
+	maxLimit float64
+	inFlight int
+	notify   chan struct{} // closed and replaced whenever capacity may have become available
+	clock    Clock
+}
+
+// LimiterToken represents a unit of work admitted by an AdaptiveLimiter. Release must be called when the work completes.
+type LimiterToken struct {
+	l        *AdaptiveLimiter
+	clock    Clock
+	start    time.Time
+	inFlight int
+	once     sync.Once
+}
+
+// NewAdaptiveLimiter creates an AdaptiveLimiter.
+//
+// Parameters:
+//   - algo: The algorithm adjusting the limit
+//   - initial: The starting limit
+//   - minLimit: The lowest limit the algorithm may set
+//   - maxLimit: The highest limit the algorithm may set
+//
+// Returns:
+//   - A pointer to a new AdaptiveLimiter instance
+//
+// Panics:
+//   - If minLimit is less than 1 or initial is not within [minLimit, maxLimit]
+func NewAdaptiveLimiter(algo LimitAlgorithm, initial int, minLimit int, maxLimit int) *AdaptiveLimiter {
+	if minLimit < 1 {
+		panic("minLimit must be greater than zero")
+	}
+	if initial < minLimit || initial > maxLimit {
+		panic("initial must be within [minLimit, maxLimit]")
+	}
+	return &AdaptiveLimiter{
+		algo:     algo,
+		limit:    float64(initial),
+		minLimit: float64(minLimit),
+		maxLimit: float64(maxLimit),
+		notify:   make(chan struct{}),
+		clock:    SystemClock,
+	}
+}
+
+// WithClock sets the Clock used to measure the latency of work units and returns the limiter for chaining. It should
+// be called before the limiter is used.
+func (l *AdaptiveLimiter) WithClock(clock Clock) *AdaptiveLimiter {
+	l.mu.Lock()
+	l.clock = clock
+	l.mu.Unlock()
+	return l
+}
+
+// Acquire blocks until the number of in-flight work units is below the limit or ctx is done.
+//
+// Returns:
+//   - A token to release when the work completes
+//   - ctx.Err() if ctx ends before capacity is available
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (*LimiterToken, error) {
+	for {
+		l.mu.Lock()
+		if t, ok := l.admit(); ok {
+			l.mu.Unlock()
+			return t, nil
+		}
+		notify := l.notify
+		l.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// TryAcquire admits a work unit if the limiter has capacity, without blocking.
+func (l *AdaptiveLimiter) TryAcquire() (*LimiterToken, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.admit()
+}
+
+// admit takes a slot if one is free. The caller must hold the lock.
+func (l *AdaptiveLimiter) admit() (*LimiterToken, bool) {
+	if l.inFlight >= int(l.limit) {
+		return nil, false
+	}
+	l.inFlight++
+	return &LimiterToken{l: l, clock: l.clock, start: l.clock.Now(), inFlight: l.inFlight}, true
+}
+
+// Release reports the outcome of the work unit and frees its slot. Only the first call has an effect.
+//
+// Parameters:
+//   - dropped: true if the work failed or timed out
+func (t *LimiterToken) Release(dropped bool) {
+	t.once.Do(func() {
+		t.l.release(LimitSample{RTT: t.clock.Now().Sub(t.start), InFlight: t.inFlight, Dropped: dropped})
+	})
+}
+
+// release frees a slot and feeds the sample to the algorithm.
+func (l *AdaptiveLimiter) release(s LimitSample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.limit = math.Max(l.minLimit, math.Min(l.maxLimit, l.algo.Update(l.limit, s)))
+
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// Limit returns the current concurrency limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the number of admitted work units that have not been released.
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAIMD(t *testing.T) {
+	a := AIMD{Timeout: 100 * time.Millisecond}
+
+	if l := a.Update(10, LimitSample{RTT: time.Millisecond, InFlight: 10}); l != 11 {
+		t.Fatalf("expected additive increase to 11, got %v", l)
+	}
+	if l := a.Update(10, LimitSample{RTT: time.Millisecond, InFlight: 2}); l != 10 {
+		t.Fatalf("expected an underused limit to hold at 10, got %v", l)
+	}
+	if l := a.Update(10, LimitSample{RTT: time.Millisecond, InFlight: 10, Dropped: true}); l != 9 {
+		t.Fatalf("expected multiplicative decrease to 9, got %v", l)
+	}
+	if l := a.Update(10, LimitSample{RTT: time.Second, InFlight: 10}); l != 9 {
+		t.Fatalf("expected a timeout to count as dropped, got %v", l)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	g := NewGradient(100, 1.5, 1)
+
+	limit := 20.0
+	for i := 0; i < 50; i++ {
+		limit = g.Update(limit, LimitSample{RTT: 10 * time.Millisecond, InFlight: int(limit)})
+	}
+	if limit <= 20 {
+		t.Fatalf("expected the limit to grow under stable latency, got %v", limit)
+	}
+
+	grown := limit
+	limit = g.Update(limit, LimitSample{RTT: 100 * time.Millisecond, InFlight: int(limit)})
+	if limit >= grown {
+		t.Fatalf("expected the limit to shrink when latency spikes, %v >= %v", limit, grown)
+	}
+}
+
+func TestAdaptiveLimiterBlocksAtLimit(t *testing.T) {
+	l := NewAdaptiveLimiter(AIMD{}, 2, 1, 2)
+
+	a, _ := l.Acquire(context.Background())
+	b, _ := l.Acquire(context.Background())
+	if _, ok := l.TryAcquire(); ok {
+		t.Fatal("expected limiter to be full")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); err == nil {
+		t.Fatal("expected acquire to time out")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c, err := l.Acquire(context.Background())
+		if err == nil {
+			c.Release(false)
+		}
+		close(done)
+	}()
+	a.Release(true) // dropped: the limit backs off to the minimum of 1
+	b.Release(false)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a released slot to admit the waiter")
+	}
+	if l.InFlight() != 0 {
+		t.Fatalf("expected nothing in flight, got %d", l.InFlight())
+	}
+}
+
+func TestWorkerPoolWithConcurrencyLimiter(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	limiter := NewAdaptiveLimiter(AIMD{}, 2, 1, 2)
+
+	wp := NewWorkerPool(func(v int) int {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return v
+	}, 20, 8).WithConcurrencyLimiter(limiter, func(v int) bool { return v < 0 })
+
+	for i := 0; i < 20; i++ {
+		wp.Post(i)
+	}
+	wp.Close()
+	for _, ok := wp.Next(); ok; _, ok = wp.Next() {
+	}
+
+	if peak > 2 {
+		t.Fatalf("expected at most 2 concurrent calls, got %d", peak)
+	}
+}
+
+// recordingAlgorithm is a LimitAlgorithm that keeps the limit and records every sample.
+type recordingAlgorithm struct {
+	samples []LimitSample
+}
+
+func (a *recordingAlgorithm) Update(limit float64, s LimitSample) float64 {
+	a.samples = append(a.samples, s)
+	return limit
+}
+
+func TestAdaptiveLimiterClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	algo := &recordingAlgorithm{}
+	l := NewAdaptiveLimiter(algo, 2, 1, 2).WithClock(clock)
+
+	token, ok := l.TryAcquire()
+	if !ok {
+		t.Fatal("expected the limiter to admit the work")
+	}
+	clock.Advance(250 * time.Millisecond)
+	token.Release(false)
+
+	if len(algo.samples) != 1 || algo.samples[0].RTT != 250*time.Millisecond {
+		t.Fatalf("expected one sample with an RTT of 250ms, got %+v", algo.samples)
+	}
+}
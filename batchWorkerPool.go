@@ -0,0 +1,187 @@
+package util
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBatchResultCount is returned by BatchWorkerPool.Do when the batch function did not return exactly one result per
+// item.
+var ErrBatchResultCount = errors.New("batch function returned the wrong number of results")
+
+// BatchWorkerPool is a generic worker pool that groups posted work items into batches and processes each batch with a
+// single call to its function. A batch is flushed when it reaches batchSize items or when its oldest item has waited
+// for the linger duration, whichever comes first. Each item's result is routed back to the caller that posted it.
+type BatchWorkerPool[W any, R any] struct {
+	work    chan batchItem[W, R]
+	batches chan []batchItem[W, R]
+	f       func([]W) []R
+	size    int
+	linger  time.Duration
+	count   atomic.Int32
+	clock   Clock
+}
+
+// batchItem pairs a posted work item with the channel its result is delivered on.
+type batchItem[W any, R any] struct {
+	w     W
+	reply chan R
+}
+
+// NewBatchWorkerPool creates and initializes a new BatchWorkerPool.
+//
+// Parameters:
+//   - f: The batch function; it must return exactly one result per input, in the same order, or every item of the
+//     batch fails
+//   - batchSize: The number of items that triggers an immediate flush
+//   - linger: The maximum time an item waits for its batch to fill up
+//   - backlog: The buffer size of the work channel and of the queue of flushed batches
+//   - numWorkers: The number of workers running f concurrently
+//
+// Returns:
+//   - A pointer to a new BatchWorkerPool instance
+//
+// Panics:
+//   - If batchSize or numWorkers is less than 1, linger is not positive, or backlog is negative
+func NewBatchWorkerPool[W any, R any](f func([]W) []R, batchSize int, linger time.Duration, backlog int, numWorkers int) *BatchWorkerPool[W, R] {
+	if batchSize < 1 {
+		panic("batchSize must be greater than zero")
+	}
+	if linger <= 0 {
+		panic("linger must be greater than zero")
+	}
+	if backlog < 0 {
+		panic("backlog must be greater than -1")
+	}
+	if numWorkers < 1 {
+		panic("numWorkers must be greater than zero")
+	}
+	pool := &BatchWorkerPool[W, R]{
+		work:    make(chan batchItem[W, R], backlog),
+		batches: make(chan []batchItem[W, R], backlog),
+		f:       f,
+		size:    batchSize,
+		linger:  linger,
+		clock:   SystemClock,
+	}
+
+	go pool.collect()
+
+	// start n workers
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			for batch := range pool.batches { // until closed
+				pool.process(batch)
+			}
+		}()
+	}
+	return pool
+}
+
+// WithClock sets the Clock used for the linger time and returns the pool for chaining. It must be called before the
+// first Post.
+func (bp *BatchWorkerPool[W, R]) WithClock(clock Clock) *BatchWorkerPool[W, R] {
+	bp.clock = clock
+	return bp
+}
+
+// collect groups incoming work items into batches and hands them to the workers.
+func (bp *BatchWorkerPool[W, R]) collect() {
+	var batch []batchItem[W, R]
+	var timer Timer
+	var timeout <-chan time.Time
+
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if len(batch) > 0 {
+			bp.batches <- batch
+			batch = nil
+		}
+	}
+
+	for {
+		select {
+		case item, ok := <-bp.work:
+			if !ok {
+				flush()
+				close(bp.batches)
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) == 1 {
+				timer = bp.clock.NewTimer(bp.linger)
+				timeout = timer.C()
+			}
+			if len(batch) >= bp.size {
+				flush()
+			}
+		case <-timeout:
+			flush()
+		}
+	}
+}
+
+// process runs the batch function and routes each result to the caller that posted the matching item. If the function
+// returns the wrong number of results, no result can be matched to its item, so every reply channel of the batch is
+// closed instead.
+func (bp *BatchWorkerPool[W, R]) process(batch []batchItem[W, R]) {
+	in := make([]W, len(batch))
+	for i, item := range batch {
+		in[i] = item.w
+	}
+
+	out := bp.f(in)
+	for i, item := range batch {
+		bp.count.Add(-1)
+		if len(out) != len(in) {
+			close(item.reply)
+			continue
+		}
+		item.reply <- out[i]
+	}
+}
+
+// Close terminates the work channel, signaling that no more work items will be submitted. Items already posted are
+// flushed in a final batch.
+func (bp *BatchWorkerPool[W, R]) Close() {
+	close(bp.work)
+}
+
+// Post submits a work item and increments the active work count. This will block when the work channel is full.
+//
+// Returns:
+//   - A channel that receives the item's result once its batch has been processed, or is closed without a value if
+//     the batch function returned the wrong number of results
+func (bp *BatchWorkerPool[W, R]) Post(w W) <-chan R {
+	reply := make(chan R, 1)
+	bp.count.Add(1)
+	bp.work <- batchItem[W, R]{w: w, reply: reply}
+	return reply
+}
+
+// Do submits a work item and blocks until its result is available.
+//
+// Returns:
+//   - The item's result
+//   - ErrBatchResultCount if the batch function returned the wrong number of results
+func (bp *BatchWorkerPool[W, R]) Do(w W) (R, error) {
+	r, ok := <-bp.Post(w)
+	if !ok {
+		return r, ErrBatchResultCount
+	}
+	return r, nil
+}
+
+// Len returns the current number of posted work items whose results have not been delivered yet.
+func (bp *BatchWorkerPool[W, R]) Len() int32 {
+	return bp.count.Load()
+}
+
+// IsActive checks if the BatchWorkerPool has work items whose results have not been delivered yet.
+func (bp *BatchWorkerPool[W, R]) IsActive() bool {
+	return bp.count.Load() > 0
+}
@@ -0,0 +1,148 @@
+package util
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchWorkerPoolFlushOnSize(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	bp := NewBatchWorkerPool(func(in []int) []int {
+		mu.Lock()
+		sizes = append(sizes, len(in))
+		mu.Unlock()
+		out := make([]int, len(in))
+		for i, v := range in {
+			out[i] = v * 10
+		}
+		return out
+	}, 4, time.Hour, 8, 1)
+
+	replies := make([]<-chan int, 8)
+	for i := range replies {
+		replies[i] = bp.Post(i)
+	}
+
+	for i, r := range replies {
+		select {
+		case v := <-r:
+			if v != i*10 {
+				t.Fatalf("item %d: expected %d, got %d", i, i*10, v)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("item %d: timed out; batches should flush on size", i)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sizes) != 2 || sizes[0] != 4 || sizes[1] != 4 {
+		t.Fatalf("expected two batches of 4, got %v", sizes)
+	}
+	if bp.IsActive() {
+		t.Fatal("expected pool to be idle")
+	}
+	bp.Close()
+}
+
+func TestBatchWorkerPoolFlushOnLinger(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	bp := NewBatchWorkerPool(func(in []string) []int {
+		out := make([]int, len(in))
+		for i, s := range in {
+			out[i] = len(s)
+		}
+		return out
+	}, 100, 20*time.Millisecond, 0, 2).WithClock(clock)
+	defer bp.Close()
+
+	a := bp.Post("abc")
+	b := bp.Post("de")
+
+	clock.BlockUntil(1) // the linger timer of the batch
+	select {
+	case v := <-a:
+		t.Fatalf("expected batch to linger, got %d", v)
+	default:
+	}
+	clock.Advance(20 * time.Millisecond)
+
+	if v := <-a; v != 3 {
+		t.Fatalf("expected 3, got %d", v)
+	}
+	if v := <-b; v != 2 {
+		t.Fatalf("expected 2, got %d", v)
+	}
+}
+
+func TestBatchWorkerPoolConcurrentCallers(t *testing.T) {
+	bp := NewBatchWorkerPool(func(in []int) []int {
+		out := make([]int, len(in))
+		for i, v := range in {
+			out[i] = -v
+		}
+		return out
+	}, 5, 5*time.Millisecond, 4, 2)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func(v int) {
+			defer wg.Done()
+			if r, err := bp.Do(v); err != nil || r != -v {
+				t.Errorf("caller %d: expected %d, got %d, %v", v, -v, r, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	bp.Close()
+}
+
+func TestBatchWorkerPoolCloseFlushes(t *testing.T) {
+	bp := NewBatchWorkerPool(func(in []int) []int { return in }, 10, time.Hour, 4, 1)
+
+	r := bp.Post(7)
+	bp.Close()
+
+	select {
+	case v := <-r:
+		if v != 7 {
+			t.Fatalf("expected 7, got %d", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out; Close should flush the pending batch")
+	}
+}
+
+func TestBatchWorkerPoolResultCountMismatch(t *testing.T) {
+	bp := NewBatchWorkerPool(func(in []int) []int {
+		if in[0] < 0 {
+			return nil
+		}
+		return in
+	}, 2, time.Hour, 4, 1)
+	defer bp.Close()
+
+	r := bp.Post(-1)
+	if _, err := bp.Do(-2); !errors.Is(err, ErrBatchResultCount) {
+		t.Fatalf("expected ErrBatchResultCount, got %v", err)
+	}
+	if v, ok := <-r; ok {
+		t.Fatalf("expected the reply channel to be closed, got %d", v)
+	}
+	if bp.IsActive() {
+		t.Fatal("expected pool to be idle")
+	}
+
+	// the worker survives a failed batch
+	r = bp.Post(3)
+	if v, err := bp.Do(4); err != nil || v != 4 {
+		t.Fatalf("expected 4,nil got %d,%v", v, err)
+	}
+	if v := <-r; v != 3 {
+		t.Fatalf("expected 3, got %d", v)
+	}
+}
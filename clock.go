@@ -0,0 +1,242 @@
+package util
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the passage of time so that time-dependent types can be tested deterministically. Types that
+// depend on time accept a Clock through a WithClock method and use SystemClock by default.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the Clock counterpart of time.Timer. C returns nil for timers created with AfterFunc.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the Clock counterpart of time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// SystemClock is the Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTimer(d time.Duration) Timer         { return systemTimer{time.NewTimer(d)} }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+type systemTimer struct{ *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+type systemTicker struct{ *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// FakeClock is a Clock whose time only moves when Advance or Set is called. Timers, tickers and AfterFunc callbacks
+// fire in time order while the clock advances: channels receive the firing time without blocking, like their time
+// package counterparts, and callbacks run synchronously on the goroutine calling Advance. FakeClock is safe for
+// concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // signaled when a waiter is added
+	now     time.Time
+	waiters []*fakeTimer // pending timers and tickers
+}
+
+// fakeTicker adapts a periodic fakeTimer to the Ticker interface.
+type fakeTicker struct{ t *fakeTimer }
+
+func (t fakeTicker) C() <-chan time.Time { return t.t.c }
+func (t fakeTicker) Stop()               { t.t.Stop() }
+
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	t.t.Reset(d)
+}
+
+// fakeTimer is a timer, ticker or AfterFunc callback of a FakeClock.
+type fakeTimer struct {
+	clock  *FakeClock
+	c      chan time.Time
+	fn     func()
+	when   time.Time
+	period time.Duration // non-zero for tickers
+}
+
+// NewFakeClock creates a FakeClock set to start.
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current fake time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the fake time once the clock has advanced by d.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer creates a Timer that fires once the clock has advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker creates a Ticker that fires every d of fake time.
+//
+// Panics:
+//   - If d is not positive
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return fakeTicker{t}
+}
+
+// AfterFunc calls f once the clock has advanced by d.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &fakeTimer{clock: c, fn: f}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer that falls due on the way in time order.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+	c.Set(target)
+}
+
+// Set moves the clock to t, firing every timer due by then in time order. Setting an earlier time fires nothing.
+func (c *FakeClock) Set(t time.Time) {
+	for {
+		c.mu.Lock()
+		if len(c.waiters) == 0 || c.waiters[0].when.After(t) {
+			if t.After(c.now) {
+				c.now = t
+			}
+			c.mu.Unlock()
+			return
+		}
+		w := c.waiters[0]
+		if w.when.After(c.now) {
+			c.now = w.when
+		}
+		now := c.now
+		c.remove(w)
+		if w.period > 0 {
+			w.when = w.when.Add(w.period)
+			c.insert(w)
+		}
+		c.mu.Unlock()
+
+		w.fire(now)
+	}
+}
+
+// BlockUntil blocks until at least n timers, tickers or AfterFunc callbacks are pending. Tests use it to wait for a
+// goroutine to start waiting on the clock before advancing it.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// insert adds a waiter, keeping waiters ordered by due time. The caller must hold the lock.
+func (c *FakeClock) insert(t *fakeTimer) {
+	i := sort.Search(len(c.waiters), func(i int) bool { return c.waiters[i].when.After(t.when) })
+	c.waiters = append(c.waiters, nil)
+	copy(c.waiters[i+1:], c.waiters[i:])
+	c.waiters[i] = t
+	c.cond.Broadcast()
+}
+
+// remove deletes a waiter and reports whether it was pending. The caller must hold the lock.
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	for i, w := range c.waiters {
+		if w == t {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fire delivers a due timer: the time is sent without blocking, or the callback is called.
+func (t *fakeTimer) fire(now time.Time) {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
+
+// Reset reschedules the timer to fire d after the current fake time. A timer with a non-positive d fires immediately:
+// a channel receives the time at once and a callback runs on its own goroutine, as with the time package.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	active := t.clock.remove(t)
+	if t.period > 0 {
+		t.period = d
+	}
+	if d <= 0 && t.period == 0 {
+		now := t.clock.now
+		t.clock.mu.Unlock()
+		if t.fn != nil {
+			go t.fn()
+		} else {
+			t.fire(now)
+		}
+		return active
+	}
+	t.when = t.clock.now.Add(d)
+	t.clock.insert(t)
+	t.clock.mu.Unlock()
+	return active
+}
@@ -0,0 +1,165 @@
+package util
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClockTimersFireInOrder(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var fired []int
+	c.AfterFunc(30*time.Millisecond, func() { fired = append(fired, 3) })
+	c.AfterFunc(10*time.Millisecond, func() { fired = append(fired, 1) })
+	stopped := c.AfterFunc(20*time.Millisecond, func() { fired = append(fired, 2) })
+	timer := c.NewTimer(25 * time.Millisecond)
+
+	if !stopped.Stop() {
+		t.Fatal("expected Stop to report a pending timer")
+	}
+	c.Advance(15 * time.Millisecond)
+	if !slices.Equal(fired, []int{1}) {
+		t.Fatalf("expected only the first callback, got %v", fired)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("expected the timer not to fire yet")
+	default:
+	}
+
+	c.Advance(15 * time.Millisecond)
+	if !slices.Equal(fired, []int{1, 3}) {
+		t.Fatalf("expected the stopped callback to be skipped, got %v", fired)
+	}
+	if got := <-timer.C(); !got.Equal(fakeEpoch.Add(25 * time.Millisecond)) {
+		t.Fatalf("expected the timer to fire at its due time, got %v", got)
+	}
+	if !c.Now().Equal(fakeEpoch.Add(30 * time.Millisecond)) {
+		t.Fatalf("unexpected time %v", c.Now())
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	ticker := c.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	var ticks int
+	for i := 0; i < 3; i++ {
+		c.Advance(10 * time.Millisecond)
+		<-ticker.C()
+		ticks++
+	}
+	c.Advance(50 * time.Millisecond) // ticks are dropped while nobody receives, like time.Ticker
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("expected at most one buffered tick")
+	default:
+	}
+	if ticks != 3 {
+		t.Fatalf("expected 3 ticks, got %d", ticks)
+	}
+}
+
+func TestFakeClockBlockUntil(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	done := make(chan time.Time)
+	go func() { done <- <-c.After(time.Hour) }()
+
+	c.BlockUntil(1)
+	c.Advance(time.Hour)
+	if got := <-done; !got.Equal(fakeEpoch.Add(time.Hour)) {
+		t.Fatalf("expected the waiter to wake at the fake hour, got %v", got)
+	}
+}
+
+func TestDebouncerWithFakeClock(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var calls atomic.Int32
+	d := NewDebouncer(time.Minute, func() (int32, error) { return calls.Add(1), nil }).WithClock(c)
+
+	v1, _ := d.GetValue()
+	c.Advance(59 * time.Second)
+	v2, _ := d.GetValue()
+	c.Advance(2 * time.Second)
+	v3, _ := d.GetValue()
+	if v1 != 1 || v2 != 1 || v3 != 2 {
+		t.Fatalf("expected 1, 1, 2, got %d, %d, %d", v1, v2, v3)
+	}
+}
+
+func TestPubSubDebouncerWithFakeClock(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var calls atomic.Int32
+	d, cancel := NewPubSubDebouncer(time.Minute, func() (int32, error) { return calls.Add(1), nil })
+	defer cancel()
+	d.WithClock(c)
+
+	ch := d.Register()
+	defer d.Unregister(ch)
+	for want := int32(1); want <= 3; want++ {
+		if v := <-ch; v != want {
+			t.Fatalf("expected %d, got %d", want, v)
+		}
+		c.BlockUntil(1) // the fetcher waits for its next round
+		c.Advance(time.Minute)
+	}
+}
+
+func TestDebounceWithFakeClock(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var r recorder
+	d := Debounce(r.record, 20*time.Millisecond, DebounceOptions{MaxWait: 50 * time.Millisecond}).WithClock(c)
+
+	for i := 1; i <= 8; i++ {
+		d.Call(i)
+		c.Advance(10 * time.Millisecond)
+	}
+	if got := r.get(); !slices.Equal(got, []int{5}) {
+		t.Fatalf("expected MaxWait to deliver 5 after 50ms, got %v", got)
+	}
+	c.Advance(20 * time.Millisecond)
+	if got := r.get(); !slices.Equal(got, []int{5, 8}) {
+		t.Fatalf("expected the trailing call to deliver 8, got %v", got)
+	}
+}
+
+func TestTokenBucketWithFakeClock(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	tb := NewTokenBucket(10, 2).WithClock(c)
+
+	if !tb.Allow() || !tb.Allow() || tb.Allow() {
+		t.Fatal("expected exactly the burst to be allowed")
+	}
+	c.Advance(100 * time.Millisecond)
+	if !tb.Allow() {
+		t.Fatal("expected a token after 100ms at 10/s")
+	}
+
+	done := make(chan error)
+	go func() { done <- tb.Wait(context.Background()) }()
+	c.BlockUntil(1)
+	c.Advance(100 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("expected Wait to succeed once the clock advanced, got %v", err)
+	}
+}
+
+func TestLoadingCacheWithFakeClock(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var loads atomic.Int32
+	cache := NewLoadingCache(func(_ context.Context, k string) (int32, error) { return loads.Add(1), nil }, time.Hour, 10).WithClock(c)
+
+	_, _ = cache.Get(context.Background(), "k")
+	c.Advance(59 * time.Minute)
+	_, _ = cache.Get(context.Background(), "k")
+	c.Advance(time.Minute)
+	if v, _ := cache.Get(context.Background(), "k"); v != 2 {
+		t.Fatalf("expected a reload after the TTL, got %d", v)
+	}
+}
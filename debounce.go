@@ -4,20 +4,58 @@ package util
 import (
 	"context"
 	"math"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrorPolicy determines what Debouncer.GetValue returns when the fetcher fails.
+type ErrorPolicy int
+
+const (
+	// ServeStale returns the last successfully fetched value with a nil error as long as it is no older than the
+	// configured MaxStale age (unlimited by default). The fetch error is available from LastError. If there is no
+	// value young enough, the error is returned. This is the default policy.
+	ServeStale ErrorPolicy = iota
+	// ReturnError returns the zero value of T together with the fetch error.
+	ReturnError
+	// NegativeCache returns the fetch error like ReturnError and caches it for the configured negative TTL, during
+	// which GetValue returns the cached error without calling the fetcher.
+	NegativeCache
+)
+
 // Debouncer is a generic struct that caches a value with a delay to prevent excessive recomputation or fetching.
 // It uses a fetcher function to retrieve the value and a timeout to manage the validity of the cached value.
+// It is safe for concurrent use; when the cached value expires, only one fetch runs and concurrent callers share it.
+// How fetch errors are reported is controlled by its ErrorPolicy.
+//
+// By default the first caller after expiry waits for the fetch. With refresh-ahead or background refresh enabled,
+// the value is refreshed asynchronously before it expires so that callers get a cached value immediately; call Close
+// to stop background work.
 type Debouncer[T any] struct {
-	fetcher   func() (T, error)
-	lastValue T
-	timeOut   time.Time // time when value can be renewed
-	delay     time.Duration
+	fetcher     func(ctx context.Context) (T, error)
+	timeout     time.Duration // per-fetch timeout; 0 means none
+	mu          sync.Mutex
+	lastValue   T
+	timeOut     time.Time // time when value can be renewed
+	delay       time.Duration
+	flight      singleflight[struct{}, T]
+	policy      ErrorPolicy
+	maxStale    time.Duration // 0 means stale values are served indefinitely
+	negativeTTL time.Duration
+	lastErr     error
+	errTimeOut  time.Time // time when a negatively cached error expires
+	lastSuccess time.Time
+	ahead       float64     // fraction of delay after which GetValue triggers an asynchronous refresh; 0 disables
+	refreshing  atomic.Bool // an asynchronous refresh is running
+	clock       Clock
+	context     context.Context
+	cancel      context.CancelFunc
 }
 
 // NewDebouncer creates a new Debouncer with a specified delay and a fetcher function to retrieve values.
+// The Debouncer uses the ServeStale error policy; use WithErrorPolicy to change it.
 //
 // Parameters:
 //   - delay: The duration to wait before allowing a new value to be fetched
@@ -26,41 +64,290 @@ type Debouncer[T any] struct {
 // Returns:
 //   - A pointer to a new Debouncer instance
 func NewDebouncer[T any](delay time.Duration, fetcher func() (T, error)) *Debouncer[T] {
+	return NewDebouncerCtx(delay, withoutContext(fetcher))
+}
+
+// NewDebouncerCtx is like NewDebouncer for a fetcher that accepts a context. The context is canceled when the
+// Debouncer is closed and, if WithFetchTimeout is set, when the fetch takes too long.
+//
+// Parameters:
+//   - delay: The duration to wait before allowing a new value to be fetched
+//   - fetcher: A function that returns a value of type T and an error; it should honor ctx cancellation
+//
+// Returns:
+//   - A pointer to a new Debouncer instance
+func NewDebouncerCtx[T any](delay time.Duration, fetcher func(ctx context.Context) (T, error)) *Debouncer[T] {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Debouncer[T]{
 		fetcher: fetcher,
 		delay:   delay,
+		clock:   SystemClock,
+		context: ctx,
+		cancel:  cancel,
 	}
 }
 
+// WithFetchTimeout limits each fetch to timeout and returns the Debouncer for chaining. A fetch exceeding it sees its
+// context canceled; it fails like any other fetch once the fetcher returns. 0 disables the limit.
+func (d *Debouncer[T]) WithFetchTimeout(timeout time.Duration) *Debouncer[T] {
+	d.mu.Lock()
+	d.timeout = timeout
+	d.mu.Unlock()
+	return d
+}
+
+// WithClock sets the Clock used for expiry and background refresh and returns the Debouncer for chaining. It should be
+// called before the Debouncer is used, and before WithBackgroundRefresh.
+func (d *Debouncer[T]) WithClock(clock Clock) *Debouncer[T] {
+	d.mu.Lock()
+	d.clock = clock
+	d.mu.Unlock()
+	return d
+}
+
+// WithErrorPolicy sets how fetch errors are reported and returns the Debouncer for chaining.
+// It should be called before the Debouncer is used.
+func (d *Debouncer[T]) WithErrorPolicy(policy ErrorPolicy) *Debouncer[T] {
+	d.mu.Lock()
+	d.policy = policy
+	d.mu.Unlock()
+	return d
+}
+
+// WithMaxStale sets the maximum age of a value served by the ServeStale policy after a failed fetch and returns the
+// Debouncer for chaining. The age is measured from the last successful fetch; 0 means no limit.
+func (d *Debouncer[T]) WithMaxStale(maxStale time.Duration) *Debouncer[T] {
+	d.mu.Lock()
+	d.maxStale = maxStale
+	d.mu.Unlock()
+	return d
+}
+
+// WithNegativeTTL sets how long the NegativeCache policy caches a fetch error and returns the Debouncer for chaining.
+func (d *Debouncer[T]) WithNegativeTTL(ttl time.Duration) *Debouncer[T] {
+	d.mu.Lock()
+	d.negativeTTL = ttl
+	d.mu.Unlock()
+	return d
+}
+
+// WithRefreshAhead enables refresh-ahead and returns the Debouncer for chaining. Once a cached value is older than
+// fraction of the delay, GetValue returns it immediately and starts an asynchronous refresh, so that callers rarely
+// wait for a fetch. Values outside (0, 1) disable refresh-ahead.
+func (d *Debouncer[T]) WithRefreshAhead(fraction float64) *Debouncer[T] {
+	d.mu.Lock()
+	if fraction > 0 && fraction < 1 {
+		d.ahead = fraction
+	} else {
+		d.ahead = 0
+	}
+	d.mu.Unlock()
+	return d
+}
+
+// WithBackgroundRefresh starts a goroutine that refreshes the value on a fixed schedule, beginning immediately, and
+// returns the Debouncer for chaining. Choose an interval shorter than the delay so that the value never expires.
+// The goroutine runs until Close is called.
+//
+// Panics:
+//   - If interval is not positive
+func (d *Debouncer[T]) WithBackgroundRefresh(interval time.Duration) *Debouncer[T] {
+	if interval <= 0 {
+		panic("interval must be greater than zero")
+	}
+	go func() {
+		ticker := d.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			_, _, _ = d.flight.Do(struct{}{}, func() (T, error) { return d.fetch(0) })
+			select {
+			case <-d.context.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+	return d
+}
+
+// Close stops background refreshing and cancels the context of any in-flight fetch. The Debouncer remains usable for
+// synchronous fetches, but fetchers created with NewDebouncerCtx receive a canceled context from then on.
+func (d *Debouncer[T]) Close() {
+	d.cancel()
+}
+
 // GetValue retrieves the cached value or fetches a new one if the timeout has expired, updating the cache and timeout.
 // If the timeout has expired, it calls the fetcher function to get a new value, updates the cache,
-// and resets the timeout.
+// and resets the timeout. A failed fetch is reported according to the ErrorPolicy.
+// With refresh-ahead enabled, an aging value is returned immediately while a refresh runs in the background.
 //
 // Returns:
-//   - The cached value of type T
-//   - An error if one occurred during fetching (nil if using cached value)
+//   - The cached or freshly fetched value of type T, a stale value under ServeStale, or the zero value on error
+//   - The fetch error, unless the ServeStale policy served a stale value instead
 func (d *Debouncer[T]) GetValue() (T, error) {
-	if time.Now().After(d.timeOut) {
-		value, err := d.fetcher()
-		if err == nil {
-			d.timeOut = time.Now().Add(d.delay)
-			d.lastValue = value
+	d.mu.Lock()
+	now := d.clock.Now()
+	if !now.After(d.timeOut) {
+		value := d.lastValue
+		refresh := d.ahead > 0 && now.Sub(d.lastSuccess) >= d.aheadAge()
+		d.mu.Unlock()
+		if refresh {
+			d.refreshAhead()
 		}
+		return value, nil
+	}
+	if d.policy == NegativeCache && d.lastErr != nil && now.Before(d.errTimeOut) {
+		err := d.lastErr
+		d.mu.Unlock()
+		var zero T
+		return zero, err
+	}
+	d.mu.Unlock()
+
+	value, err, _ := d.flight.Do(struct{}{}, func() (T, error) { return d.fetch(d.delay) })
+	if err == nil {
+		return value, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.policy == ServeStale && !d.lastSuccess.IsZero() && (d.maxStale <= 0 || d.clock.Now().Sub(d.lastSuccess) <= d.maxStale) {
+		return d.lastValue, nil
 	}
-	return d.lastValue, nil
+	var zero T
+	return zero, err
+}
+
+// aheadAge returns the value age at which refresh-ahead starts. The caller must hold the lock.
+func (d *Debouncer[T]) aheadAge() time.Duration {
+	return time.Duration(d.ahead * float64(d.delay))
+}
+
+// refreshAhead starts an asynchronous refresh unless one is already running.
+func (d *Debouncer[T]) refreshAhead() {
+	if !d.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer d.refreshing.Store(false)
+		d.mu.Lock()
+		minAge := d.aheadAge()
+		d.mu.Unlock()
+		_, _, _ = d.flight.Do(struct{}{}, func() (T, error) { return d.fetch(minAge) })
+	}()
+}
+
+// fetch calls the fetcher function and records the outcome, unless a value younger than minAge has been fetched in
+// the meantime by another caller. It must only be called through the singleflight group.
+func (d *Debouncer[T]) fetch(minAge time.Duration) (T, error) {
+	d.mu.Lock()
+	if !d.lastSuccess.IsZero() && d.clock.Now().Sub(d.lastSuccess) < minAge { // another caller refreshed the value
+		value := d.lastValue
+		d.mu.Unlock()
+		return value, nil
+	}
+	timeout := d.timeout
+	d.mu.Unlock()
+
+	value, err := callFetcher(d.context, timeout, d.fetcher)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.clock.Now()
+	if err != nil {
+		d.lastErr = err
+		d.errTimeOut = now.Add(d.negativeTTL)
+		return value, err
+	}
+	d.lastErr = nil
+	d.lastSuccess = now
+	d.timeOut = now.Add(d.delay)
+	d.lastValue = value
+	return value, nil
+}
+
+// LastError returns the error of the most recent fetch, or nil if it succeeded.
+func (d *Debouncer[T]) LastError() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lastErr
+}
+
+// LastSuccess returns the time of the most recent successful fetch, or the zero time if no fetch has succeeded.
+func (d *Debouncer[T]) LastSuccess() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lastSuccess
+}
+
+// withoutContext adapts a fetcher without a context parameter. A nil fetcher stays nil.
+func withoutContext[T any](fetcher func() (T, error)) func(context.Context) (T, error) {
+	if fetcher == nil {
+		return nil
+	}
+	return func(context.Context) (T, error) { return fetcher() }
+}
+
+// callFetcher calls fetcher with a context derived from ctx, limited to timeout if it is positive.
+func callFetcher[T any](ctx context.Context, timeout time.Duration, fetcher func(context.Context) (T, error)) (T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return fetcher(ctx)
+}
+
+// DebounceEvent is delivered to channels returned by PubSubDebouncer.RegisterEvents. It carries either a changed value
+// or, when Err is non-nil, the error of a failed fetch.
+type DebounceEvent[T any] struct {
+	Value T
+	Err   error
+	Time  time.Time
+}
+
+// DebounceHealth reports the freshness of a PubSubDebouncer's value.
+type DebounceHealth struct {
+	Stale               bool      // the most recent fetch failed, so the value may be out of date
+	StaleSince          time.Time // when the current run of failed fetches started; zero unless Stale
+	LastSuccess         time.Time // when a fetch last succeeded; zero if none has
+	LastError           error     // the error of the most recent fetch; nil unless Stale
+	ConsecutiveFailures int
 }
 
 // PubSubDebouncer is a utility for debouncing values with a pub-sub mechanism for notifying listeners.
 // It holds the latest value and enforces a delay duration before updates are allowed.
 // Values are fetched using a provided fetcher function, and a context is used for cancellation.
 // It uses a PubSub instance to broadcast updates to registered listeners.
-type PubSubDebouncer[T comparable] struct {
+// It is safe for concurrent use; GetValue and the background fetcher share a single in-flight fetch.
+//
+// Whether a new value counts as a change is decided by an equality function, which can be replaced with WithEqual.
+// The default compares comparable types with ==, falls back to reflect.DeepEqual for types such as structs holding
+// slices or maps, and treats float64 values within 1e-8 and float32 values within 1e-5 as equal.
+//
+// Fetch errors are published to channels returned by RegisterEvents and summarized by Health. With WithBackoff, the
+// background fetcher waits exponentially longer after consecutive failures.
+type PubSubDebouncer[T any] struct {
 	lastValue   T
+	equal       func(a, b T) bool
 	listeners   *PubSub[T]
+	events      *PubSub[DebounceEvent[T]]
 	delay       time.Duration
+	maxBackoff  time.Duration // 0 disables backoff
+	factor      float64
+	health      DebounceHealth
 	timeOut     time.Time
-	fetcherFunc func() (T, error)
+	fetcherFunc func(ctx context.Context) (T, error)
+	timeout     time.Duration // per-fetch timeout; 0 means none
 	context     context.Context
+	running     bool // the background fetcher goroutine is running
+	flight      singleflight[struct{}, T]
+	clock       Clock
+	push        *pushState // non-nil for debouncers fed by a push source
+	replay      bool       // new listeners receive the current value on registration
+	unpublished bool       // lastValue changed since listeners were last notified
+	changedAt   time.Time  // when lastValue last changed
+	publishing  sync.Mutex // serializes broadcasts; acquired before the RWMutex
 	sync.RWMutex
 }
 
@@ -78,7 +365,25 @@ type PubSubDebouncer[T comparable] struct {
 //
 // Panics:
 //   - If the delay is less than 100 milliseconds
-func NewPubSubDebouncer[T comparable](delay time.Duration, fetcher func() (T, error)) (*PubSubDebouncer[T], context.CancelFunc) {
+func NewPubSubDebouncer[T any](delay time.Duration, fetcher func() (T, error)) (*PubSubDebouncer[T], context.CancelFunc) {
+	return NewPubSubDebouncerCtx(delay, withoutContext(fetcher))
+}
+
+// NewPubSubDebouncerCtx is like NewPubSubDebouncer for a fetcher that accepts a context. The context is canceled when
+// the returned cancel function is called, so that a hung fetch does not keep the background fetcher alive, and, if
+// WithFetchTimeout is set, when the fetch takes too long.
+//
+// Parameters:
+//   - delay: The minimum duration between value updates (must be >= 100ms)
+//   - fetcher: A function that returns a value of type T and an error; it should honor ctx cancellation
+//
+// Returns:
+//   - A pointer to a new PubSubDebouncer instance
+//   - A context.CancelFunc that stops the debouncer and cancels any in-flight fetch
+//
+// Panics:
+//   - If the delay is less than 100 milliseconds
+func NewPubSubDebouncerCtx[T any](delay time.Duration, fetcher func(ctx context.Context) (T, error)) (*PubSubDebouncer[T], context.CancelFunc) {
 	if delay < 100*time.Millisecond {
 		panic("delay must be greater >= 100 milliseconds")
 	}
@@ -86,34 +391,160 @@ func NewPubSubDebouncer[T comparable](delay time.Duration, fetcher func() (T, er
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
 	ret := &PubSubDebouncer[T]{
+		equal:       defaultEqual[T],
 		listeners:   NewPubSub[T](),
+		events:      NewPubSub[DebounceEvent[T]](),
 		delay:       delay,
 		fetcherFunc: fetcher,
 		context:     ctx,
+		clock:       SystemClock,
 	}
 
 	return ret, cancelFunc
 }
 
 // GetValue retrieves the current value, refreshing it using the fetcher function if the timeout has expired.
-// This method is thread-safe and uses a read lock to protect access to the debouncer's state.
+// This method is thread-safe; concurrent callers that find the value expired share a single fetch.
+// GetValue never notifies listeners itself, so it cannot block on a listener that is not reading; a changed value it
+// fetched is broadcast by the background fetcher after its next fetch. A debouncer fed by a push source returns the
+// latest value without fetching; its polling fallback only runs when the source goes quiet.
 //
 // Returns:
 //   - The current value of type T (either cached or freshly fetched)
-//   - An error if one occurred during fetching (nil if using cached value or if fetch was successful)
+//   - An error if one occurred during fetching, together with the zero value of T
 func (d *PubSubDebouncer[T]) GetValue() (T, error) {
 	d.RLock()
-	defer d.RUnlock()
-	var err error
+	value, fresh := d.lastValue, !d.clock.Now().After(d.timeOut)
+	canFetch := d.fetcherFunc != nil && d.push == nil
+	d.RUnlock()
+	if fresh || !canFetch { // a push source is polled by its consumer, not by readers
+		return value, nil
+	}
 
-	if time.Now().After(d.timeOut) {
-		d.lastValue, err = d.fetcherFunc()
-		if err == nil {
-			d.timeOut = time.Now().Add(d.delay)
+	value, err, _ := d.flight.Do(struct{}{}, d.fetch)
+	return value, err
+}
+
+// fetch calls the fetcher function, stores a successful result and records the outcome in the health status. It must
+// only be called through the singleflight group so that at most one fetch runs at a time. It does not notify
+// listeners, so that callers sharing the fetch never wait on a listener; see publish.
+func (d *PubSubDebouncer[T]) fetch() (T, error) {
+	d.RLock()
+	fetcher, timeout := d.fetcherFunc, d.timeout
+	d.RUnlock()
+	value, err := callFetcher(d.context, timeout, fetcher)
+	now := d.clock.Now()
+	if err != nil {
+		d.Lock()
+		if d.health.ConsecutiveFailures == 0 {
+			d.health.StaleSince = now
 		}
+		d.health.ConsecutiveFailures++
+		d.health.Stale = true
+		d.health.LastError = err
+		d.Unlock()
+
+		var zero T
+		return zero, err
+	}
+
+	d.Lock()
+	d.health = DebounceHealth{LastSuccess: now}
+	d.store(value, now)
+	d.Unlock()
+	return value, nil
+}
+
+// store updates the stored value if it differs from the previous value according to the equality function, marking
+// it for publishing, and resets the timeout. The caller must hold the lock.
+func (d *PubSubDebouncer[T]) store(value T, now time.Time) {
+	if !d.equal(d.lastValue, value) {
+		d.lastValue = value
+		d.unpublished = true
+		d.changedAt = now
 	}
+	d.timeOut = now.Add(d.delay)
+}
+
+// publish broadcasts a stored value that listeners have not been notified of yet and, if err is non-nil, the error of
+// a failed fetch. It must be called outside the singleflight group, because broadcasts block until every listener has
+// room for the message. Broadcasts are serialized, so listeners receive changes in the order they were stored, and a
+// listener that acquires the publishing lock after unregistering knows that no broadcast still holds its channel.
+func (d *PubSubDebouncer[T]) publish(err error) {
+	d.publishing.Lock()
+	defer d.publishing.Unlock()
+	d.Lock()
+	value, changed, changedAt := d.lastValue, d.unpublished, d.changedAt
+	d.unpublished = false
+	now := d.clock.Now()
+	d.Unlock()
+
+	if changed {
+		d.listeners.Broadcast(value)
+		d.events.Broadcast(DebounceEvent[T]{Value: value, Time: changedAt})
+	}
+	if err != nil {
+		d.events.Broadcast(DebounceEvent[T]{Err: err, Time: now})
+	}
+}
+
+// Health returns the freshness of the value as of the most recent fetch.
+func (d *PubSubDebouncer[T]) Health() DebounceHealth {
+	d.RLock()
+	defer d.RUnlock()
+	return d.health
+}
+
+// WithFetchTimeout limits each fetch to timeout and returns the debouncer for chaining. A fetch exceeding it sees its
+// context canceled; it fails like any other fetch once the fetcher returns. 0 disables the limit.
+func (d *PubSubDebouncer[T]) WithFetchTimeout(timeout time.Duration) *PubSubDebouncer[T] {
+	d.Lock()
+	d.timeout = timeout
+	d.Unlock()
+	return d
+}
+
+// WithClock sets the Clock used for expiry and the background fetcher's schedule and returns the debouncer for
+// chaining. It should be called before the debouncer is used.
+func (d *PubSubDebouncer[T]) WithClock(clock Clock) *PubSubDebouncer[T] {
+	d.Lock()
+	d.clock = clock
+	d.Unlock()
+	if d.push != nil {
+		d.push.wake()
+	}
+	return d
+}
+
+// WithBackoff enables exponential backoff of the background fetcher and returns the debouncer for chaining. After n
+// consecutive failed fetches, the fetcher waits delay*factor^n, capped at maxDelay, before trying again. It should be
+// called before the debouncer is used.
+//
+// Panics:
+//   - If factor is less than 1 or maxDelay is less than the debounce delay
+func (d *PubSubDebouncer[T]) WithBackoff(maxDelay time.Duration, factor float64) *PubSubDebouncer[T] {
+	if factor < 1 {
+		panic("factor must be >= 1")
+	}
+	if maxDelay < d.delay {
+		panic("maxDelay must be >= delay")
+	}
+	d.Lock()
+	d.maxBackoff = maxDelay
+	d.factor = factor
+	d.Unlock()
+	return d
+}
 
-	return d.lastValue, err
+// nextDelay returns how long the background fetcher waits before its next fetch.
+func (d *PubSubDebouncer[T]) nextDelay() time.Duration {
+	d.RLock()
+	defer d.RUnlock()
+	if d.maxBackoff == 0 || d.health.ConsecutiveFailures == 0 {
+		return d.delay
+	}
+	backoff := float64(d.delay) * math.Pow(d.factor, float64(d.health.ConsecutiveFailures))
+	return time.Duration(math.Min(backoff, float64(d.maxBackoff)))
 }
 
 // GetValueMust retrieves the latest value from the debouncer and panics if an error occurs during fetching.
@@ -132,59 +563,153 @@ func (d *PubSubDebouncer[T]) GetValueMust() T {
 	return value
 }
 
-// SetValue updates the stored value if it differs from the previous value, considering type-specific precision thresholds.
-// Triggers a broadcast to notify listeners if the value changes and adjusts the timeout duration.
-// For floating-point types (float32, float64), it uses a small epsilon value to determine if the value has changed.
-// For other types, it uses direct equality comparison.
+// WithEqual replaces the function deciding whether a new value equals the current one and returns the debouncer for
+// chaining. SetValue only stores and broadcasts values that are not equal to the current value, so the first argument
+// is always the last broadcast value. See RelativeTolerance, AbsoluteTolerance and PercentChange for ready-made
+// functions. It should be called before the debouncer is used.
+func (d *PubSubDebouncer[T]) WithEqual(equal func(a, b T) bool) *PubSubDebouncer[T] {
+	d.Lock()
+	d.equal = equal
+	d.Unlock()
+	return d
+}
+
+// SetValue updates the stored value if it differs from the previous value according to the equality function.
+// Triggers a broadcast to notify listeners if the value changes, or if a change fetched by GetValue has not been
+// broadcast yet, and adjusts the timeout duration.
 //
 // Parameters:
 //   - value: The new value to store and potentially broadcast to listeners
 func (d *PubSubDebouncer[T]) SetValue(value T) {
-	doBroadcast := false
-
 	d.Lock()
+	d.store(value, d.clock.Now())
+	d.Unlock()
+	d.publish(nil)
+}
 
-	switch any(value).(type) {
+// defaultEqual is the default equality function of PubSubDebouncer. Floats are compared with a small absolute
+// epsilon, comparable values with == and everything else, including interface values holding incomparable types,
+// with reflect.DeepEqual.
+func defaultEqual[T any](a, b T) (equal bool) {
+	switch av := any(a).(type) {
 	case float64:
-		if math.Abs(any(d.lastValue).(float64)-any(value).(float64)) > 0.00000001 {
-			d.lastValue = value
-			doBroadcast = true
+		if bv, ok := any(b).(float64); ok {
+			return math.Abs(av-bv) <= 0.00000001
 		}
 	case float32:
-		if math.Abs(float64(any(d.lastValue).(float32)-any(value).(float32))) > 0.00001 {
-			d.lastValue = value
-			doBroadcast = true
-		}
-	default:
-		if d.lastValue != value {
-			d.lastValue = value
-			doBroadcast = true
+		if bv, ok := any(b).(float32); ok {
+			return math.Abs(float64(av-bv)) <= 0.00001
 		}
 	}
 
-	d.timeOut = time.Now().Add(d.delay)
-	d.Unlock()
-
-	if doBroadcast {
-		d.listeners.Broadcast(value)
-		//log.Printf("changed value = %v", value)
+	if !reflect.TypeFor[T]().Comparable() {
+		return reflect.DeepEqual(a, b)
 	}
+	defer func() {
+		if recover() != nil { // == panics on interface values holding incomparable types
+			equal = reflect.DeepEqual(a, b)
+		}
+	}()
+	return any(a) == any(b)
 }
 
 // Register registers a new listener channel for receiving debounced values. Starts the fetcher if no listeners are active.
 // This method automatically starts the background fetcher goroutine if this is the first active listener.
+// With WithReplay, the channel already holds the current value if one has been set.
 //
 // Returns:
 //   - A receive-only channel that will receive updates when the debounced value changes
 func (d *PubSubDebouncer[T]) Register() <-chan T {
-	wasActive := d.listeners.IsActive()
-	newListener := d.listeners.Register(1)
-	if !wasActive {
-		go d.fetcher() // start the fetcher if it wasn't running before
+	d.Lock()
+	defer d.Unlock()
+	var newListener <-chan T
+	if d.replay && !d.timeOut.IsZero() { // SetValue has run, so lastValue is a real value
+		newListener = d.listeners.registerPrimed(1, d.lastValue)
+	} else {
+		newListener = d.listeners.Register(1)
 	}
+	d.startFetcher()
 	return newListener
 }
 
+// WithReplay makes new listeners receive the current value as soon as they register, instead of waiting for the next
+// change, and returns the debouncer for chaining. A listener registering while a change is being broadcast may receive
+// the new value twice. It should be called before the debouncer is used.
+func (d *PubSubDebouncer[T]) WithReplay() *PubSubDebouncer[T] {
+	d.Lock()
+	d.replay = true
+	d.Unlock()
+	return d
+}
+
+// Subscribe calls fn with every change of the value, on a dedicated goroutine, until ctx is done, at which point the
+// subscription is unregistered. Like Register, it starts the background fetcher, and with WithReplay fn is first
+// called with the current value.
+//
+// Parameters:
+//   - ctx: The context ending the subscription
+//   - fn: The function receiving values; calls are sequential, and a slow fn delays broadcasts to other listeners
+func (d *PubSubDebouncer[T]) Subscribe(ctx context.Context, fn func(T)) {
+	ch := d.Register()
+	go func() {
+		defer func() {
+			d.Unregister(ch)
+			// a broadcast that picked the channel up before it was unregistered still sends to it; keep draining until
+			// the publishing lock shows that every such broadcast has finished
+			idle := make(chan struct{})
+			go func() {
+				d.publishing.Lock()
+				d.publishing.Unlock()
+				close(idle)
+			}()
+			for {
+				select {
+				case <-ch:
+				case <-idle:
+					return
+				}
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v := <-ch:
+				fn(v)
+			}
+		}
+	}()
+}
+
+// RegisterEvents registers a new listener channel receiving both changed values and fetch errors. Like Register, it
+// starts the background fetcher if it isn't running.
+//
+// Returns:
+//   - A receive-only channel of value-or-error events
+func (d *PubSubDebouncer[T]) RegisterEvents() <-chan DebounceEvent[T] {
+	d.Lock()
+	defer d.Unlock()
+	newListener := d.events.Register(1)
+	d.startFetcher()
+	return newListener
+}
+
+// UnregisterEvents removes a channel previously returned by RegisterEvents.
+func (d *PubSubDebouncer[T]) UnregisterEvents(c <-chan DebounceEvent[T]) {
+	d.events.Unregister(c)
+}
+
+// startFetcher starts the background fetcher if it isn't running. The caller must hold the lock.
+func (d *PubSubDebouncer[T]) startFetcher() {
+	if d.push != nil { // the push consumer runs from construction
+		return
+	}
+	if !d.running {
+		d.running = true
+		go d.fetcher() // start the fetcher if it wasn't running before
+	}
+}
+
 // Unregister removes a specified listener channel from the debouncer's PubSub, closing the channel and freeing resources.
 // If this was the last active listener, the background fetcher goroutine will automatically stop on its next iteration.
 //
@@ -196,23 +721,34 @@ func (d *PubSubDebouncer[T]) Unregister(c <-chan T) {
 
 // fetcher periodically fetches a value using fetcherFunc and updates the debouncer's state, notifying listeners if active.
 // This is an internal method that runs as a goroutine, continuously fetching values at the specified delay interval.
+// After failed fetches it waits according to the backoff policy instead of the delay.
 // It automatically exits when either the context is canceled or there are no more active listeners.
 func (d *PubSubDebouncer[T]) fetcher() {
 	//log.Printf("starting fetcher")
 	for {
-		value, err := d.fetcherFunc()
-		if err == nil {
-			d.SetValue(value)
-		}
+		_, err, _ := d.flight.Do(struct{}{}, d.fetch)
+		d.publish(err)
+		delay := d.nextDelay()
+		d.RLock()
+		timer := d.clock.NewTimer(delay)
+		d.RUnlock()
 		select {
 		case <-d.context.Done():
+			timer.Stop()
+			d.Lock()
+			d.running = false
+			d.Unlock()
 			return
-		case <-time.After(d.delay):
+		case <-timer.C():
 		}
 
-		if !d.listeners.IsActive() {
+		d.Lock()
+		if !d.listeners.IsActive() && !d.events.IsActive() {
 			//		log.Printf("no listeners, exiting fetcher")
+			d.running = false
+			d.Unlock()
 			return
 		}
+		d.Unlock()
 	}
 }
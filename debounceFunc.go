@@ -0,0 +1,198 @@
+// Package util provides utility functions and types for common operations.
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// DebounceOptions configures Debounce.
+//
+// Leading calls the function at the start of a burst of calls; Trailing calls it once the burst has been quiet for
+// the wait duration. If neither is set, Trailing is assumed. MaxWait caps how long calls may be held back while a
+// burst continues: once MaxWait has passed since the burst started, or since the last forced call, the function is
+// called even though the burst has not ended. Zero disables the cap.
+type DebounceOptions struct {
+	Leading  bool
+	Trailing bool
+	MaxWait  time.Duration
+}
+
+// DebouncedFunc coalesces bursts of calls into fewer calls of a wrapped function, which receives the argument of the
+// latest call. Unlike Debouncer, which caches a fetched value, DebouncedFunc debounces events such as file changes or
+// UI input. It is safe for concurrent use. The wrapped function runs either on the goroutine calling Call (leading
+// edge and Flush) or on a timer goroutine (trailing edge and MaxWait).
+type DebouncedFunc[T any] struct {
+	fn      func(T)
+	wait    time.Duration
+	opts    DebounceOptions
+	mu      sync.Mutex
+	active  bool // a burst is in progress
+	pending bool // calls have been made that the function has not seen yet
+	arg     T
+	clock   Clock
+	waitT   Timer
+	maxT    Timer
+	waitSeq uint64 // invalidates callbacks of stopped wait timers
+	maxSeq  uint64 // invalidates callbacks of stopped max-wait timers
+}
+
+// Debounce wraps fn so that a burst of calls, separated by less than wait, results in a single call on the leading
+// and/or trailing edge of the burst.
+//
+// Parameters:
+//   - fn: The function to call with the latest argument
+//   - wait: The quiet period that ends a burst
+//   - opts: The edges to fire on and the optional MaxWait cap
+//
+// Returns:
+//   - A pointer to a new DebouncedFunc instance
+//
+// Panics:
+//   - If wait is not positive or MaxWait is negative
+func Debounce[T any](fn func(T), wait time.Duration, opts DebounceOptions) *DebouncedFunc[T] {
+	if wait <= 0 {
+		panic("wait must be greater than zero")
+	}
+	if opts.MaxWait < 0 {
+		panic("maxWait must not be negative")
+	}
+	if !opts.Leading && !opts.Trailing {
+		opts.Trailing = true
+	}
+	return &DebouncedFunc[T]{fn: fn, wait: wait, opts: opts, clock: SystemClock}
+}
+
+// Throttle wraps fn so that it is called at most once per interval: immediately for the first call and then with the
+// latest argument at the end of each interval in which further calls were made.
+//
+// Parameters:
+//   - fn: The function to call with the latest argument
+//   - interval: The minimum time between calls of fn during a continuous burst
+//
+// Returns:
+//   - A pointer to a new DebouncedFunc instance
+//
+// Panics:
+//   - If interval is not positive
+func Throttle[T any](fn func(T), interval time.Duration) *DebouncedFunc[T] {
+	return Debounce(fn, interval, DebounceOptions{Leading: true, Trailing: true, MaxWait: interval})
+}
+
+// WithClock sets the Clock driving the wait and MaxWait timers and returns the DebouncedFunc for chaining. It should be
+// called before Call is used.
+func (d *DebouncedFunc[T]) WithClock(clock Clock) *DebouncedFunc[T] {
+	d.mu.Lock()
+	d.clock = clock
+	d.mu.Unlock()
+	return d
+}
+
+// Call records a call with argument v, starting a burst or extending the current one.
+func (d *DebouncedFunc[T]) Call(v T) {
+	d.mu.Lock()
+	d.arg = v
+	invoke := false
+	if !d.active {
+		d.active = true
+		if d.opts.Leading {
+			invoke = true
+		} else {
+			d.pending = true
+		}
+	} else {
+		d.pending = true
+	}
+	if d.opts.MaxWait > 0 && d.maxT == nil {
+		d.maxSeq++
+		seq := d.maxSeq
+		d.maxT = d.clock.AfterFunc(d.opts.MaxWait, func() { d.onMaxWait(seq) })
+	}
+	if d.waitT != nil {
+		d.waitT.Stop()
+	}
+	d.waitSeq++
+	seq := d.waitSeq
+	d.waitT = d.clock.AfterFunc(d.wait, func() { d.onWait(seq) })
+	d.mu.Unlock()
+
+	if invoke {
+		d.fn(v)
+	}
+}
+
+// onWait ends the burst once it has been quiet for the wait duration.
+func (d *DebouncedFunc[T]) onWait(seq uint64) {
+	d.mu.Lock()
+	if seq != d.waitSeq || !d.active {
+		d.mu.Unlock()
+		return
+	}
+	invoke, arg := d.pending && d.opts.Trailing, d.arg
+	d.reset()
+	d.mu.Unlock()
+
+	if invoke {
+		d.fn(arg)
+	}
+}
+
+// onMaxWait forces a call when a burst has held back calls for MaxWait.
+func (d *DebouncedFunc[T]) onMaxWait(seq uint64) {
+	d.mu.Lock()
+	if seq != d.maxSeq || !d.active {
+		d.mu.Unlock()
+		return
+	}
+	d.maxT = nil // the next call in this burst starts a new max-wait window
+	invoke, arg := d.pending, d.arg
+	d.pending = false
+	d.mu.Unlock()
+
+	if invoke {
+		d.fn(arg)
+	}
+}
+
+// reset ends the current burst and stops its timers. The caller must hold the lock.
+func (d *DebouncedFunc[T]) reset() {
+	if d.waitT != nil {
+		d.waitT.Stop()
+		d.waitT = nil
+	}
+	if d.maxT != nil {
+		d.maxT.Stop()
+		d.maxT = nil
+	}
+	d.waitSeq++
+	d.maxSeq++
+	d.active = false
+	d.pending = false
+}
+
+// Flush ends the current burst immediately, calling the function with the latest argument if there are calls it has
+// not seen yet.
+func (d *DebouncedFunc[T]) Flush() {
+	d.mu.Lock()
+	invoke, arg := d.pending, d.arg
+	d.reset()
+	d.mu.Unlock()
+
+	if invoke {
+		d.fn(arg)
+	}
+}
+
+// Cancel ends the current burst and discards any calls the function has not seen yet.
+func (d *DebouncedFunc[T]) Cancel() {
+	d.mu.Lock()
+	d.reset()
+	d.mu.Unlock()
+}
+
+// Pending reports whether there are calls the function has not seen yet.
+func (d *DebouncedFunc[T]) Pending() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pending
+}
@@ -0,0 +1,132 @@
+package util
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects the arguments a debounced function was called with.
+type recorder struct {
+	mu    sync.Mutex
+	calls []int
+}
+
+func (r *recorder) record(v int) {
+	r.mu.Lock()
+	r.calls = append(r.calls, v)
+	r.mu.Unlock()
+}
+
+func (r *recorder) get() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
+
+func TestDebounceTrailing(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var r recorder
+	d := Debounce(r.record, 20*time.Millisecond, DebounceOptions{}).WithClock(c)
+
+	for i := 1; i <= 5; i++ {
+		d.Call(i)
+		c.Advance(2 * time.Millisecond)
+	}
+	if len(r.get()) != 0 || !d.Pending() {
+		t.Fatalf("expected no calls during the burst, got %v", r.get())
+	}
+
+	c.Advance(17 * time.Millisecond) // the last call was 2ms ago
+	if len(r.get()) != 0 {
+		t.Fatalf("expected no call before the burst has been quiet for the wait, got %v", r.get())
+	}
+	c.Advance(time.Millisecond)
+	if got := r.get(); !slices.Equal(got, []int{5}) {
+		t.Fatalf("expected a single trailing call with 5, got %v", got)
+	}
+	if d.Pending() {
+		t.Fatal("expected nothing pending after the trailing call")
+	}
+}
+
+func TestDebounceLeading(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var r recorder
+	d := Debounce(r.record, 20*time.Millisecond, DebounceOptions{Leading: true}).WithClock(c)
+
+	d.Call(1)
+	d.Call(2)
+	d.Call(3)
+	if got := r.get(); !slices.Equal(got, []int{1}) {
+		t.Fatalf("expected a single leading call with 1, got %v", got)
+	}
+
+	c.Advance(20 * time.Millisecond)
+	if got := r.get(); !slices.Equal(got, []int{1}) {
+		t.Fatalf("expected no trailing call, got %v", got)
+	}
+
+	d.Call(4)
+	if got := r.get(); !slices.Equal(got, []int{1, 4}) {
+		t.Fatalf("expected a new burst to fire on its leading edge, got %v", got)
+	}
+	d.Cancel()
+}
+
+func TestDebounceMaxWait(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var r recorder
+	d := Debounce(r.record, 20*time.Millisecond, DebounceOptions{Trailing: true, MaxWait: 50 * time.Millisecond}).WithClock(c)
+
+	// a continuous burst of calls every 5ms, twice as long as MaxWait
+	for i := 1; i <= 20; i++ {
+		d.Call(i)
+		c.Advance(5 * time.Millisecond)
+	}
+	if got := r.get(); !slices.Equal(got, []int{10, 20}) {
+		t.Fatalf("expected MaxWait to force calls with 10 and 20 at 50ms and 100ms, got %v", got)
+	}
+
+	c.Advance(20 * time.Millisecond)
+	if got := r.get(); !slices.Equal(got, []int{10, 20}) {
+		t.Fatalf("expected no trailing call once MaxWait delivered the latest value, got %v", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var r recorder
+	th := Throttle(r.record, 30*time.Millisecond).WithClock(c)
+
+	// calls every 5ms for 100ms
+	for i := 1; i <= 20; i++ {
+		th.Call(i)
+		c.Advance(5 * time.Millisecond)
+	}
+	c.Advance(30 * time.Millisecond)
+
+	if got := r.get(); !slices.Equal(got, []int{1, 6, 12, 18, 20}) {
+		t.Fatalf("expected the leading 1, the latest value every 30ms and the trailing 20, got %v", got)
+	}
+}
+
+func TestDebounceFlushAndCancel(t *testing.T) {
+	var r recorder
+	d := Debounce(r.record, time.Hour, DebounceOptions{})
+
+	d.Call(1)
+	d.Call(2)
+	d.Flush()
+	if got := r.get(); !slices.Equal(got, []int{2}) {
+		t.Fatalf("expected Flush to deliver 2, got %v", got)
+	}
+
+	d.Call(3)
+	d.Cancel()
+	d.Flush()
+	if got := r.get(); !slices.Equal(got, []int{2}) {
+		t.Fatalf("expected Cancel to discard 3, got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package util
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// pushState holds the configuration of a PubSubDebouncer fed by a push source.
+type pushState struct {
+	quiet    time.Duration // how long the source must be silent before the fallback polls; 0 disables polling
+	reconfig chan struct{} // wakes the consumer after WithPollFallback
+}
+
+// NewPubSubDebouncerFromChan creates a PubSubDebouncer fed by values pushed on source instead of a polled fetcher.
+// Values are applied with the same semantics as polled ones: at most one value per delay reaches SetValue, which is
+// the latest value received (a burst is published on its leading edge and then once per delay), values equal to the
+// current one are not broadcast, and every registered listener receives each change. The source is drained from the
+// start, whether or not listeners are registered, until the returned cancel function is called. Values pushed before
+// the first Register are therefore only visible through GetValue, or to listeners through WithReplay.
+//
+// Parameters:
+//   - delay: The minimum duration between published values (must be >= 100ms)
+//   - source: The channel delivering new values; closing it keeps the last value
+//
+// Returns:
+//   - A pointer to a new PubSubDebouncer instance
+//   - A context.CancelFunc that stops consuming the source
+//
+// Panics:
+//   - If the delay is less than 100 milliseconds
+func NewPubSubDebouncerFromChan[T any](delay time.Duration, source <-chan T) (*PubSubDebouncer[T], context.CancelFunc) {
+	d, cancel := NewPubSubDebouncerCtx[T](delay, nil)
+	d.push = &pushState{reconfig: make(chan struct{}, 1)}
+	go d.consume(source)
+	return d, cancel
+}
+
+// NewPubSubDebouncerFromSeq is like NewPubSubDebouncerFromChan for a source that is an iterator, such as a change
+// stream. The iterator runs on its own goroutine; it is stopped when the returned cancel function is called, at the
+// latest when it yields its next value.
+func NewPubSubDebouncerFromSeq[T any](delay time.Duration, source iter.Seq[T]) (*PubSubDebouncer[T], context.CancelFunc) {
+	ch := make(chan T)
+	d, cancel := NewPubSubDebouncerFromChan(delay, ch)
+	go func() {
+		defer close(ch)
+		for v := range source {
+			select {
+			case ch <- v:
+			case <-d.context.Done():
+				return
+			}
+		}
+	}()
+	return d, cancel
+}
+
+// WithPollFallback makes a push-fed debouncer poll fetcher whenever the source has been silent for quiet, for example
+// because a change stream disconnected, and returns the debouncer for chaining. Polls repeat every delay, or as set by
+// WithBackoff after failures, and stop as soon as the source pushes again; GetValue never polls. As with
+// NewPubSubDebouncerCtx, the context passed to fetcher is canceled by the cancel function and after the timeout set
+// by WithFetchTimeout. Fetch errors are reported like those of a polling debouncer, through RegisterEvents and Health.
+//
+// Panics:
+//   - If the debouncer was not created from a push source or quiet is not positive
+func (d *PubSubDebouncer[T]) WithPollFallback(quiet time.Duration, fetcher func(ctx context.Context) (T, error)) *PubSubDebouncer[T] {
+	if d.push == nil {
+		panic("WithPollFallback requires a push source")
+	}
+	if quiet <= 0 {
+		panic("quiet must be greater than zero")
+	}
+	d.Lock()
+	d.fetcherFunc = fetcher
+	d.push.quiet = quiet
+	d.Unlock()
+	d.push.wake()
+	return d
+}
+
+// wake signals the push consumer to pick up changed configuration.
+func (p *pushState) wake() {
+	select {
+	case p.reconfig <- struct{}{}:
+	default:
+	}
+}
+
+// consume applies values pushed on source, publishing at most one per delay, and polls the fallback fetcher while the
+// source is silent. It runs until the context is canceled.
+func (d *PubSubDebouncer[T]) consume(source <-chan T) {
+	var (
+		pending     T
+		lastPublish time.Time
+		lastPush    time.Time // zero until the first push or reconfiguration
+		publish     Timer     // armed while a value waits for the end of the delay
+		poll        Timer     // armed while a polling fallback is configured
+	)
+	stop := func(t Timer) Timer {
+		if t != nil {
+			t.Stop()
+		}
+		return nil
+	}
+
+	config := func() (Clock, time.Duration) { // both may be changed by With methods while the consumer runs
+		d.RLock()
+		defer d.RUnlock()
+		return d.clock, d.push.quiet
+	}
+	armPoll := func(wait func(quiet time.Duration) time.Duration) {
+		poll = stop(poll)
+		if clock, quiet := config(); quiet > 0 {
+			poll = clock.NewTimer(wait(quiet))
+		}
+	}
+
+	for {
+		var publishC, pollC <-chan time.Time
+		if publish != nil {
+			publishC = publish.C()
+		}
+		if poll != nil {
+			pollC = poll.C()
+		}
+
+		select {
+		case <-d.context.Done():
+			stop(publish)
+			stop(poll)
+			return
+
+		case v, ok := <-source:
+			if !ok {
+				source = nil // a nil channel blocks, leaving the timers in charge
+				continue
+			}
+			clock, _ := config()
+			now := clock.Now()
+			lastPush = now
+			if publish == nil && now.Sub(lastPublish) >= d.delay {
+				d.SetValue(v)
+				lastPublish = now
+			} else {
+				pending = v
+				if publish == nil {
+					publish = clock.NewTimer(lastPublish.Add(d.delay).Sub(now))
+				}
+			}
+			armPoll(func(quiet time.Duration) time.Duration { return quiet })
+
+		case <-publishC:
+			publish = nil
+			d.SetValue(pending)
+			clock, _ := config()
+			lastPublish = clock.Now()
+
+		case <-d.push.reconfig:
+			clock, _ := config()
+			if lastPush.IsZero() {
+				lastPush = clock.Now()
+			}
+			armPoll(func(quiet time.Duration) time.Duration { return max(quiet-clock.Now().Sub(lastPush), 0) })
+
+		case <-pollC:
+			poll = nil
+			_, err, _ := d.flight.Do(struct{}{}, d.fetch)
+			d.publish(err)
+			armPoll(func(time.Duration) time.Duration { return d.nextDelay() })
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPubSubDebouncerFromChanCoalescesAndFallsBack(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	src := make(chan int)
+	d, cancel := NewPubSubDebouncerFromChan(time.Second, src)
+	defer cancel()
+	d.WithClock(clock)
+	ch := d.Register()
+	defer d.Unregister(ch)
+	events := d.RegisterEvents() // before the burst, so that no value event is still being broadcast later
+	defer d.UnregisterEvents(events)
+
+	src <- 1 // leading edge of a burst is published at once
+	if v := <-ch; v != 1 {
+		t.Fatalf("expected 1, got %d", v)
+	}
+	if ev := <-events; ev.Value != 1 {
+		t.Fatalf("expected a value event for 1, got %+v", ev)
+	}
+	src <- 2
+	src <- 3
+	src <- 3
+	clock.BlockUntil(1) // the publish timer for the end of the delay
+	clock.Advance(time.Second)
+	if v := <-ch; v != 3 {
+		t.Fatalf("expected the burst to collapse to its latest value 3, got %d", v)
+	}
+	if ev := <-events; ev.Value != 3 {
+		t.Fatalf("expected a value event for 3, got %+v", ev)
+	}
+	if v, err := d.GetValue(); err != nil || v != 3 {
+		t.Fatalf("expected GetValue to return the latest pushed value, got %d, %v", v, err)
+	}
+
+	fetches := 0
+	d.WithBackoff(time.Hour, 10) // the retry after the failed poll waits 10s
+	d.WithPollFallback(time.Minute, func(context.Context) (int, error) {
+		fetches++
+		if fetches == 1 {
+			return 0, errors.New("source down")
+		}
+		return 99, nil
+	})
+
+	clock.BlockUntil(1) // the poll timer armed by the reconfiguration
+	// the last push may have been taken at either end of the delay, so the poll is due a minute after one of them
+	clock.Advance(time.Minute)
+	if ev := <-events; ev.Err == nil {
+		t.Fatalf("expected the failed poll to be reported, got %+v", ev)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Second)
+	if v := <-ch; v != 99 {
+		t.Fatalf("expected the polled value 99, got %d", v)
+	}
+	if ev := <-events; ev.Value != 99 {
+		t.Fatalf("expected a value event for 99, got %+v", ev)
+	}
+
+	src <- 5 // pushing again takes over from polling
+	if v := <-ch; v != 5 {
+		t.Fatalf("expected 5, got %d", v)
+	}
+	if ev := <-events; ev.Value != 5 {
+		t.Fatalf("expected a value event for 5, got %+v", ev)
+	}
+	if fetches != 2 {
+		t.Fatalf("expected 2 polls, got %d", fetches)
+	}
+}
+
+func TestPubSubDebouncerFromSeq(t *testing.T) {
+	gate := make(chan struct{})
+	yielded := make(chan struct{})
+	seq := func(yield func(string) bool) {
+		<-gate
+		defer close(yielded)
+		for _, v := range []string{"a", "a", "b", "c", "c"} { // once the last c is taken, the first one has been applied
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	clock := NewFakeClock(fakeEpoch)
+	d, cancel := NewPubSubDebouncerFromSeq(time.Second, seq)
+	defer cancel()
+	d.WithClock(clock)
+	ch := d.Register()
+	defer d.Unregister(ch)
+	close(gate)
+
+	if v := <-ch; v != "a" {
+		t.Fatalf("expected the leading a, got %s", v)
+	}
+	<-yielded
+	clock.BlockUntil(1) // the publish timer for the end of the delay
+	clock.Advance(time.Second)
+	if v := <-ch; v != "c" {
+		t.Fatalf("expected the trailing c, got %s", v)
+	}
+}
+
+func TestPubSubDebouncerPollFallbackOnlyWhenQuiet(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	src := make(chan int)
+	d, cancel := NewPubSubDebouncerFromChan(time.Second, src)
+	defer cancel()
+	d.WithClock(clock)
+	ch := d.Register()
+	defer d.Unregister(ch)
+
+	src <- 1
+	if v := <-ch; v != 1 {
+		t.Fatalf("expected 1, got %d", v)
+	}
+
+	deadlines := make(chan bool, 1)
+	d.WithFetchTimeout(5 * time.Second)
+	d.WithPollFallback(time.Minute, func(ctx context.Context) (int, error) {
+		_, ok := ctx.Deadline()
+		deadlines <- ok
+		return 99, nil
+	})
+	clock.BlockUntil(1) // the poll timer, due a minute after the push
+	clock.Advance(30 * time.Second)
+	// the value has expired, but the source is not quiet yet, so GetValue must not poll
+	if v, err := d.GetValue(); err != nil || v != 1 {
+		t.Fatalf("expected the pushed value without a poll, got %d, %v", v, err)
+	}
+	select {
+	case <-deadlines:
+		t.Fatal("expected no poll while the source is active")
+	default:
+	}
+
+	clock.Advance(30 * time.Second)
+	if ok := <-deadlines; !ok {
+		t.Fatal("expected the fetch timeout to apply to the polling fallback")
+	}
+	if v := <-ch; v != 99 {
+		t.Fatalf("expected the polled value 99, got %d", v)
+	}
+}
@@ -1,7 +1,9 @@
 package util
 
 import (
+    "context"
     "errors"
+    "sync"
     "sync/atomic"
     "testing"
     "time"
@@ -16,7 +18,8 @@ func TestDebouncer_GetValue_CachesWithinDelayAndRefetchesAfter(t *testing.T) {
         return v, nil
     }
 
-    d := NewDebouncer[int](50*time.Millisecond, fetcher)
+    clock := NewFakeClock(fakeEpoch)
+    d := NewDebouncer[int](50*time.Millisecond, fetcher).WithClock(clock)
 
     // First call should fetch value 1
     got, err := d.GetValue()
@@ -36,15 +39,15 @@ func TestDebouncer_GetValue_CachesWithinDelayAndRefetchesAfter(t *testing.T) {
         t.Fatalf("expected cached 1, got %v", got)
     }
 
-    // Sleep just under delay and check still cached
-    time.Sleep(20 * time.Millisecond)
+    // Advance just under delay and check still cached
+    clock.Advance(20 * time.Millisecond)
     got, _ = d.GetValue()
     if got != 1 {
         t.Fatalf("expected cached 1 before delay expiry, got %v", got)
     }
 
     // After delay, next call should refetch and return 2
-    time.Sleep(40 * time.Millisecond)
+    clock.Advance(40 * time.Millisecond)
     got, _ = d.GetValue()
     if got != 2 {
         t.Fatalf("expected refreshed 2 after delay, got %v", got)
@@ -69,7 +72,8 @@ func TestDebouncer_GetValue_IgnoresErrorsAndKeepsLastValue(t *testing.T) {
         return current, nil
     }
 
-    d := NewDebouncer[int](30*time.Millisecond, fetcher)
+    clock := NewFakeClock(fakeEpoch)
+    d := NewDebouncer[int](30*time.Millisecond, fetcher).WithClock(clock)
 
     // Initial successful fetch -> 1
     v, err := d.GetValue()
@@ -78,7 +82,7 @@ func TestDebouncer_GetValue_IgnoresErrorsAndKeepsLastValue(t *testing.T) {
     }
 
     // Force time to pass the delay
-    time.Sleep(35 * time.Millisecond)
+    clock.Advance(35 * time.Millisecond)
 
     // Next fetch fails; Debouncer's GetValue should return lastValue and nil error per implementation
     failNext = true
@@ -92,7 +96,7 @@ func TestDebouncer_GetValue_IgnoresErrorsAndKeepsLastValue(t *testing.T) {
 
     // After another delay, a successful fetch should update value to 2
     failNext = false
-    time.Sleep(35 * time.Millisecond)
+    clock.Advance(35 * time.Millisecond)
     v, err = d.GetValue()
     if err != nil || v != 2 {
         t.Fatalf("expected 2,nil after recovery, got %v,%v", v, err)
@@ -108,13 +112,15 @@ func TestPubSubDebouncer_RegisterFetchesAndPublishes(t *testing.T) {
         return int(atomic.AddInt32(&cnt, 1)), nil
     }
 
+    clock := NewFakeClock(fakeEpoch)
     d, cancel := NewPubSubDebouncer[int](delay, fetcher)
     defer cancel()
+    d.WithClock(clock)
 
     ch := d.Register()
     defer d.Unregister(ch)
 
-    // Expect periodic publishes roughly each delay; allow generous timeouts to avoid flakiness
+    // Expect a publish on registration and one per delay
     select {
     case v := <-ch:
         if v != 1 {
@@ -125,6 +131,8 @@ func TestPubSubDebouncer_RegisterFetchesAndPublishes(t *testing.T) {
     }
 
     // Next cycle should publish 2
+    clock.BlockUntil(1)
+    clock.Advance(delay)
     select {
     case v := <-ch:
         if v != 2 {
@@ -150,8 +158,10 @@ func TestPubSubDebouncer_GetValue_RespectsDelayAndErrors(t *testing.T) {
         return value, nil
     }
 
+    clock := NewFakeClock(fakeEpoch)
     d, cancel := NewPubSubDebouncer[int](delay, fetcher)
     defer cancel()
+    d.WithClock(clock)
 
     // First GetValue should fetch 1
     v, err := d.GetValue()
@@ -161,12 +171,12 @@ func TestPubSubDebouncer_GetValue_RespectsDelayAndErrors(t *testing.T) {
 
     // Within delay, should return cached 1 and not call fetcher
     v, err = d.GetValue()
-    if err != nil || v != 1 {
+    if err != nil || v != 1 || atomic.LoadInt32(&calls) != 1 {
         t.Fatalf("expected cached 1,nil got %v,%v", v, err)
     }
 
     // After delay, failure should return last value and non-nil error
-    time.Sleep(delay + 10*time.Millisecond)
+    clock.Advance(delay + 10*time.Millisecond)
     fail = true
     v, err = d.GetValue()
     if err == nil {
@@ -178,7 +188,7 @@ func TestPubSubDebouncer_GetValue_RespectsDelayAndErrors(t *testing.T) {
     }
 
     // After another delay, success should update to 2
-    time.Sleep(delay + 10*time.Millisecond)
+    clock.Advance(delay + 10*time.Millisecond)
     fail = false
     v, err = d.GetValue()
     if err != nil || v != 2 {
@@ -194,7 +204,9 @@ func TestPubSubDebouncer_CancelStopsFetcher(t *testing.T) {
         return 1, nil
     }
 
+    clock := NewFakeClock(fakeEpoch)
     d, cancel := NewPubSubDebouncer[int](delay, fetcher)
+    d.WithClock(clock)
     ch := d.Register()
 
     // Wait for first publish
@@ -204,14 +216,597 @@ func TestPubSubDebouncer_CancelStopsFetcher(t *testing.T) {
         t.Fatal("timeout waiting initial publish")
     }
 
-    // Cancel and ensure no more publishes; allow a window > delay
+    // Cancel and ensure the fetcher stops before its next cycle
+    clock.BlockUntil(1)
     cancel()
-    select {
-    case <-ch:
-        t.Fatal("received publish after cancel")
-    case <-time.After(2 * delay):
-        // ok
+    waitForTimers(t, clock, 0)
+    clock.Advance(2 * delay)
+    if c := atomic.LoadInt32(&calls); c != 1 {
+        t.Fatalf("expected no fetches after cancel, got %d", c)
     }
 
     d.Unregister(ch)
 }
+
+func TestDebouncer_GetValue_ConcurrentCallersShareOneFetch(t *testing.T) {
+    var calls int32
+    release := make(chan struct{})
+    fetcher := func() (int, error) {
+        atomic.AddInt32(&calls, 1)
+        <-release
+        return 42, nil
+    }
+
+    d := NewDebouncer[int](time.Minute, fetcher)
+
+    var wg sync.WaitGroup
+    for i := 0; i < 20; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if v, _ := d.GetValue(); v != 42 {
+                t.Errorf("expected 42, got %v", v)
+            }
+        }()
+    }
+
+    time.Sleep(20 * time.Millisecond) // let every caller block on the in-flight fetch
+    close(release)
+    wg.Wait()
+
+    if c := atomic.LoadInt32(&calls); c != 1 {
+        t.Fatalf("expected a single fetch, got %d", c)
+    }
+}
+
+func TestPubSubDebouncer_GetValue_ConcurrentCallersShareOneFetch(t *testing.T) {
+    var calls int32
+    release := make(chan struct{})
+    fetcher := func() (int, error) {
+        atomic.AddInt32(&calls, 1)
+        <-release
+        return 7, nil
+    }
+
+    d, cancel := NewPubSubDebouncer[int](time.Minute, fetcher)
+    defer cancel()
+
+    var wg sync.WaitGroup
+    for i := 0; i < 20; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if v, err := d.GetValue(); err != nil || v != 7 {
+                t.Errorf("expected 7,nil got %v,%v", v, err)
+            }
+        }()
+    }
+
+    time.Sleep(20 * time.Millisecond)
+    close(release)
+    wg.Wait()
+
+    if c := atomic.LoadInt32(&calls); c != 1 {
+        t.Fatalf("expected a single fetch, got %d", c)
+    }
+}
+
+func TestPubSubDebouncer_ConcurrentRegisterStartsOneFetcher(t *testing.T) {
+    var calls int32
+    fetcher := func() (int, error) {
+        return int(atomic.AddInt32(&calls, 1)), nil
+    }
+
+    d, cancel := NewPubSubDebouncer[int](time.Minute, fetcher)
+    defer cancel()
+
+    var wg sync.WaitGroup
+    channels := make(chan (<-chan int), 10)
+    for i := 0; i < 10; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            channels <- d.Register()
+        }()
+    }
+    wg.Wait()
+    close(channels)
+
+    time.Sleep(20 * time.Millisecond)
+    for ch := range channels {
+        d.Unregister(ch)
+    }
+    if c := atomic.LoadInt32(&calls); c != 1 {
+        t.Fatalf("expected one background fetcher, got %d fetches", c)
+    }
+}
+
+func TestDebouncer_ServeStale_ReturnsErrorWithoutValue(t *testing.T) {
+    boom := errors.New("boom")
+    d := NewDebouncer[int](time.Minute, func() (int, error) { return 0, boom })
+
+    if _, err := d.GetValue(); !errors.Is(err, boom) {
+        t.Fatalf("expected boom before any successful fetch, got %v", err)
+    }
+    if !errors.Is(d.LastError(), boom) {
+        t.Fatalf("expected LastError to report boom, got %v", d.LastError())
+    }
+    if !d.LastSuccess().IsZero() {
+        t.Fatal("expected no successful fetch")
+    }
+}
+
+func TestDebouncer_ServeStale_MaxStale(t *testing.T) {
+    boom := errors.New("boom")
+    clock := NewFakeClock(fakeEpoch)
+    fail := false
+    d := NewDebouncer[int](10*time.Millisecond, func() (int, error) {
+        if fail {
+            return 0, boom
+        }
+        return 1, nil
+    }).WithMaxStale(40 * time.Millisecond).WithClock(clock)
+
+    if v, err := d.GetValue(); err != nil || v != 1 {
+        t.Fatalf("expected 1,nil got %v,%v", v, err)
+    }
+    success := d.LastSuccess()
+
+    fail = true
+    clock.Advance(20 * time.Millisecond)
+    if v, err := d.GetValue(); err != nil || v != 1 {
+        t.Fatalf("expected stale 1,nil got %v,%v", v, err)
+    }
+    if !errors.Is(d.LastError(), boom) {
+        t.Fatalf("expected LastError to report boom, got %v", d.LastError())
+    }
+    if !d.LastSuccess().Equal(success) {
+        t.Fatal("expected LastSuccess to be unchanged by a failed fetch")
+    }
+
+    clock.Advance(30 * time.Millisecond)
+    if _, err := d.GetValue(); !errors.Is(err, boom) {
+        t.Fatalf("expected boom once the value exceeds MaxStale, got %v", err)
+    }
+
+    fail = false
+    if v, err := d.GetValue(); err != nil || v != 1 || d.LastError() != nil {
+        t.Fatalf("expected recovery, got %v,%v,%v", v, err, d.LastError())
+    }
+}
+
+func TestDebouncer_ReturnError(t *testing.T) {
+    boom := errors.New("boom")
+    clock := NewFakeClock(fakeEpoch)
+    fail := false
+    d := NewDebouncer[int](10*time.Millisecond, func() (int, error) {
+        if fail {
+            return 0, boom
+        }
+        return 1, nil
+    }).WithErrorPolicy(ReturnError).WithClock(clock)
+
+    _, _ = d.GetValue()
+    fail = true
+    clock.Advance(15 * time.Millisecond)
+    if v, err := d.GetValue(); !errors.Is(err, boom) || v != 0 {
+        t.Fatalf("expected 0,boom got %v,%v", v, err)
+    }
+}
+
+func TestDebouncer_NegativeCache(t *testing.T) {
+    boom := errors.New("boom")
+    clock := NewFakeClock(fakeEpoch)
+    var calls int32
+    d := NewDebouncer[int](time.Minute, func() (int, error) {
+        atomic.AddInt32(&calls, 1)
+        return 0, boom
+    }).WithErrorPolicy(NegativeCache).WithNegativeTTL(30 * time.Millisecond).WithClock(clock)
+
+    for i := 0; i < 3; i++ {
+        if _, err := d.GetValue(); !errors.Is(err, boom) {
+            t.Fatalf("expected boom, got %v", err)
+        }
+    }
+    if c := atomic.LoadInt32(&calls); c != 1 {
+        t.Fatalf("expected the error to be cached, got %d fetches", c)
+    }
+
+    clock.Advance(40 * time.Millisecond)
+    _, _ = d.GetValue()
+    if c := atomic.LoadInt32(&calls); c != 2 {
+        t.Fatalf("expected a refetch after the negative TTL, got %d fetches", c)
+    }
+}
+
+func TestDebouncer_RefreshAhead(t *testing.T) {
+    var calls int32
+    clock := NewFakeClock(fakeEpoch)
+    d := NewDebouncer[int](60*time.Millisecond, func() (int, error) {
+        return int(atomic.AddInt32(&calls, 1)), nil
+    }).WithRefreshAhead(0.5).WithClock(clock)
+    defer d.Close()
+
+    if v, _ := d.GetValue(); v != 1 {
+        t.Fatalf("expected 1, got %v", v)
+    }
+
+    // past half the delay: the cached value is served while a refresh runs
+    clock.Advance(40 * time.Millisecond)
+    if v, _ := d.GetValue(); v != 1 {
+        t.Fatalf("expected cached 1 during refresh-ahead, got %v", v)
+    }
+
+    waitFor(t, func() bool { return d.LastSuccess().Equal(clock.Now()) }) // the asynchronous refresh has stored 2
+    if v, _ := d.GetValue(); v != 2 {
+        t.Fatalf("expected refreshed 2 before expiry, got %v", v)
+    }
+    if c := atomic.LoadInt32(&calls); c != 2 {
+        t.Fatalf("expected 2 fetches, got %d", c)
+    }
+}
+
+func TestDebouncer_BackgroundRefresh(t *testing.T) {
+    var calls int32
+    clock := NewFakeClock(fakeEpoch)
+    d := NewDebouncer[int](time.Minute, func() (int, error) {
+        return int(atomic.AddInt32(&calls, 1)), nil
+    }).WithClock(clock).WithBackgroundRefresh(10 * time.Millisecond)
+
+    for i := int32(1); i <= 3; i++ {
+        waitFor(t, func() bool { return atomic.LoadInt32(&calls) == i })
+        clock.BlockUntil(1)
+        clock.Advance(10 * time.Millisecond)
+    }
+    waitFor(t, func() bool { v, _ := d.GetValue(); return v == 4 }) // one refresh per tick
+
+    d.Close()
+    waitForTimers(t, clock, 0) // the refresh goroutine has stopped its ticker
+    clock.Advance(30 * time.Millisecond)
+    if c := atomic.LoadInt32(&calls); c != 4 {
+        t.Fatalf("expected Close to stop the background refresh, fetches went from 4 to %d", c)
+    }
+}
+
+func TestPubSubDebouncer_IncomparableType(t *testing.T) {
+    type snapshot struct {
+        Names []string
+        Tags  map[string]int
+    }
+    var calls int32
+    fetcher := func() (snapshot, error) {
+        atomic.AddInt32(&calls, 1)
+        return snapshot{Names: []string{"a", "b"}, Tags: map[string]int{"x": 1}}, nil
+    }
+
+    d, cancel := NewPubSubDebouncer[snapshot](100*time.Millisecond, fetcher)
+    defer cancel()
+    ch := d.listeners.Register(10) // observe broadcasts without starting the background fetcher
+    defer d.Unregister(ch)
+
+    d.SetValue(snapshot{Names: []string{"a", "b"}, Tags: map[string]int{"x": 1}})
+    d.SetValue(snapshot{Names: []string{"a", "b", "c"}})
+
+    if v := <-ch; len(v.Names) != 2 {
+        t.Fatalf("expected the first distinct snapshot, got %v", v)
+    }
+    if v := <-ch; len(v.Names) != 3 {
+        t.Fatalf("expected the changed snapshot, got %v", v)
+    }
+    select {
+    case v := <-ch:
+        t.Fatalf("expected deep-equal values not to be broadcast, got %v", v)
+    default:
+    }
+}
+
+func TestDefaultEqual_InterfaceType(t *testing.T) {
+    tests := []struct {
+        a, b any
+        want bool
+    }{
+        {1.0, 1.0 + 1e-9, true},
+        {float32(1), float32(1.000001), true},
+        {1.0, "1", false},
+        {float32(1), 1.0, false},
+        {"a", 1.0, false},
+        {[]int{1}, []int{1}, true},
+        {nil, 1.0, false},
+    }
+    for _, tt := range tests {
+        if got := defaultEqual[any](tt.a, tt.b); got != tt.want {
+            t.Errorf("defaultEqual(%#v, %#v): expected %v, got %v", tt.a, tt.b, tt.want, got)
+        }
+    }
+
+    d, cancel := NewPubSubDebouncer[any](100*time.Millisecond, func() (any, error) { return nil, nil })
+    defer cancel()
+    d.SetValue(1.5)
+    d.SetValue("changed type") // must not panic
+}
+
+func TestPubSubDebouncer_WithEqualChangeThreshold(t *testing.T) {
+    d, cancel := NewPubSubDebouncer[float64](time.Minute, func() (float64, error) { return 100, nil })
+    defer cancel()
+    d.WithEqual(PercentChange[float64](1))
+
+    ch := d.listeners.Register(10) // observe broadcasts without starting the background fetcher
+    defer d.Unregister(ch)
+
+    for _, v := range []float64{100, 100.5, 100.9, 101.5, 102, 102.4} {
+        d.SetValue(v)
+    }
+
+    var got []float64
+    for len(ch) > 0 {
+        got = append(got, <-ch)
+    }
+    if len(got) != 2 || got[0] != 100 || got[1] != 101.5 {
+        t.Fatalf("expected broadcasts of 100 and 101.5 only, got %v", got)
+    }
+}
+
+func TestPubSubDebouncer_EventsAndHealth(t *testing.T) {
+    var calls int32
+    fail := errors.New("unavailable")
+    fetcher := func() (int, error) {
+        if atomic.AddInt32(&calls, 1) <= 2 {
+            return 0, fail
+        }
+        return 42, nil
+    }
+
+    d, cancel := NewPubSubDebouncer[int](100*time.Millisecond, fetcher)
+    defer cancel()
+    events := d.RegisterEvents()
+    defer d.UnregisterEvents(events)
+
+    for i := 0; i < 2; i++ {
+        ev := <-events
+        if !errors.Is(ev.Err, fail) {
+            t.Fatalf("event %d: expected the fetch error, got %+v", i, ev)
+        }
+        h := d.Health()
+        if !h.Stale || h.ConsecutiveFailures != i+1 || h.StaleSince.IsZero() || !errors.Is(h.LastError, fail) {
+            t.Fatalf("event %d: unexpected health %+v", i, h)
+        }
+    }
+
+    ev := <-events
+    if ev.Err != nil || ev.Value != 42 {
+        t.Fatalf("expected the value event 42, got %+v", ev)
+    }
+    h := d.Health()
+    if h.Stale || h.ConsecutiveFailures != 0 || h.LastError != nil || h.LastSuccess.IsZero() {
+        t.Fatalf("expected healthy status after a successful fetch, got %+v", h)
+    }
+}
+
+func TestPubSubDebouncer_Backoff(t *testing.T) {
+    d, cancel := NewPubSubDebouncer[int](100*time.Millisecond, func() (int, error) { return 0, errors.New("down") })
+    defer cancel()
+    d.WithBackoff(time.Second, 2)
+
+    want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+    for i, w := range want {
+        if got := d.nextDelay(); got != w*time.Millisecond {
+            t.Fatalf("after %d failures: expected delay %v, got %v", i, w*time.Millisecond, got)
+        }
+        _, _ = d.GetValue()
+    }
+}
+
+func TestDebouncer_FetchTimeout(t *testing.T) {
+    d := NewDebouncerCtx(time.Minute, func(ctx context.Context) (int, error) {
+        <-ctx.Done()
+        return 0, ctx.Err()
+    }).WithErrorPolicy(ReturnError).WithFetchTimeout(20 * time.Millisecond)
+
+    start := time.Now()
+    _, err := d.GetValue()
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected the fetch to time out, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("expected the timeout to end the fetch early, took %v", elapsed)
+    }
+}
+
+func TestDebouncer_CloseCancelsInFlightFetch(t *testing.T) {
+    started := make(chan struct{})
+    d := NewDebouncerCtx(time.Minute, func(ctx context.Context) (int, error) {
+        close(started)
+        <-ctx.Done()
+        return 0, ctx.Err()
+    }).WithErrorPolicy(ReturnError)
+
+    done := make(chan error)
+    go func() {
+        _, err := d.GetValue()
+        done <- err
+    }()
+    <-started
+    d.Close()
+    if err := <-done; !errors.Is(err, context.Canceled) {
+        t.Fatalf("expected Close to cancel the fetch, got %v", err)
+    }
+}
+
+func TestPubSubDebouncer_CancelStopsHungFetch(t *testing.T) {
+    started := make(chan struct{})
+    returned := make(chan struct{})
+    d, cancel := NewPubSubDebouncerCtx(100*time.Millisecond, func(ctx context.Context) (int, error) {
+        close(started)
+        <-ctx.Done()
+        close(returned)
+        return 0, ctx.Err()
+    })
+    ch := d.Register()
+    defer d.Unregister(ch)
+
+    <-started
+    cancel()
+    select {
+    case <-returned:
+    case <-time.After(time.Second):
+        t.Fatal("expected cancel to reach the in-flight fetch")
+    }
+
+    waitFor(t, func() bool { // the background fetcher exits
+        d.RLock()
+        defer d.RUnlock()
+        return !d.running
+    })
+}
+
+func TestPubSubDebouncer_Replay(t *testing.T) {
+    d, cancel := NewPubSubDebouncer(time.Minute, func() (int, error) { return 7, nil })
+    defer cancel()
+    d.WithReplay()
+
+    if _, err := d.GetValue(); err != nil {
+        t.Fatal(err)
+    }
+    ch := d.Register()
+    defer d.Unregister(ch)
+    select {
+    case v := <-ch:
+        if v != 7 {
+            t.Fatalf("expected the replayed value 7, got %d", v)
+        }
+    default:
+        t.Fatal("expected a late subscriber to receive the current value immediately")
+    }
+}
+
+func TestPubSubDebouncer_Subscribe(t *testing.T) {
+    d, cancel := NewPubSubDebouncer(time.Minute, func() (int, error) { return 1, nil })
+    defer cancel()
+    d.WithReplay()
+    d.SetValue(1)
+
+    ctx, stop := context.WithCancel(context.Background())
+    got := make(chan int, 10)
+    d.Subscribe(ctx, func(v int) { got <- v })
+
+    if v := <-got; v != 1 {
+        t.Fatalf("expected the replayed value 1, got %d", v)
+    }
+    d.SetValue(2)
+    if v := <-got; v != 2 {
+        t.Fatalf("expected the change to 2, got %d", v)
+    }
+
+    stop()
+    waitFor(t, func() bool { return !d.listeners.IsActive() }) // the subscription unregisters when its context ends
+    d.SetValue(3) // must not block on the unregistered subscription
+}
+
+func TestPubSubDebouncer_SubscribeEndsDuringBroadcasts(t *testing.T) {
+    for i := 0; i < 50; i++ {
+        d, cancel := NewPubSubDebouncer(time.Minute, func() (int, error) { return 0, nil })
+        other := d.listeners.Register(0) // observe broadcasts without starting the background fetcher
+        ctx, stop := context.WithCancel(context.Background())
+        d.Subscribe(ctx, func(int) {})
+
+        var wg sync.WaitGroup
+        for v := 1; v <= 4; v++ {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                d.SetValue(v * 10)
+            }()
+        }
+        stop()
+
+        received := 0
+        done := make(chan struct{})
+        go func() {
+            wg.Wait()
+            close(done)
+        }()
+        for finished := false; !finished; {
+            select {
+            case <-other:
+                received++
+            case <-done:
+                finished = true
+            case <-time.After(time.Second):
+                t.Fatal("a broadcast blocked on the ended subscription")
+            }
+        }
+        if received == 0 {
+            t.Fatal("expected the other listener to receive the changes")
+        }
+        d.Unregister(other)
+        cancel()
+    }
+}
+
+func TestPubSubDebouncer_GetValueDoesNotWaitForSlowListener(t *testing.T) {
+    clock := NewFakeClock(fakeEpoch)
+    var calls int32
+    d, cancel := NewPubSubDebouncer[int](time.Second, func() (int, error) {
+        return int(min(atomic.AddInt32(&calls, 1), 4)), nil
+    })
+    defer cancel()
+    d.WithClock(clock)
+
+    ch := d.Register()
+    defer d.Unregister(ch)
+    if v := <-ch; v != 1 {
+        t.Fatalf("expected 1, got %d", v)
+    }
+    clock.BlockUntil(1)
+    clock.Advance(time.Second) // 2 fills the listener's buffer
+    clock.BlockUntil(1)
+    clock.Advance(time.Second) // the background fetcher blocks broadcasting 3
+    waitFor(t, func() bool { return atomic.LoadInt32(&calls) == 3 })
+    clock.Advance(2 * time.Second) // 3 expires
+
+    done := make(chan int)
+    go func() {
+        v, _ := d.GetValue()
+        done <- v
+    }()
+    select {
+    case v := <-done:
+        if v != 4 {
+            t.Fatalf("expected GetValue to fetch 4, got %d", v)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("GetValue blocked on a listener that is not reading")
+    }
+
+    for _, want := range []int{2, 3} {
+        if v := <-ch; v != want {
+            t.Fatalf("expected %d, got %d", want, v)
+        }
+    }
+    clock.BlockUntil(1)
+    clock.Advance(time.Second) // the next fetch returns 4 again, but listeners have not seen it
+    if v := <-ch; v != 4 {
+        t.Fatalf("expected the background fetcher to broadcast the change GetValue fetched, got %d", v)
+    }
+}
+
+// waitFor polls cond until it holds, failing the test after a second. It waits for goroutines that react to a
+// FakeClock, which advances without waiting for them.
+func waitFor(t *testing.T, cond func() bool) {
+    t.Helper()
+    for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+        if time.Now().After(deadline) {
+            t.Fatal("timed out waiting for condition")
+        }
+    }
+}
+
+// waitForTimers waits until exactly n timers are pending on clock.
+func waitForTimers(t *testing.T, clock *FakeClock, n int) {
+    t.Helper()
+    waitFor(t, func() bool {
+        clock.mu.Lock()
+        defer clock.mu.Unlock()
+        return len(clock.waiters) == n
+    })
+}
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"sync"
+)
+
+// DurableWorkerPool is a WorkerPool backed by a Journal. Every posted item is written to the journal before it is
+// queued and acknowledged once the worker function has processed it, so items that were queued but not processed
+// when the process crashed are replayed the next time a pool is created on the same journal.
+//
+// Processing is at-least-once: an item whose work function completed but whose acknowledgement did not reach the
+// journal is processed again after a restart.
+type DurableWorkerPool[W any, R any] struct {
+	pool      *WorkerPool[JournalEntry[W], R]
+	journal   *Journal[W]
+	mu        sync.Mutex
+	replaying bool // the replay goroutine is still posting recovered items
+	closing   bool // Close was called during the replay, which closes the pool after its last item
+	err       error
+}
+
+// NewDurableWorkerPool creates and initializes a new DurableWorkerPool. Pending items recovered from the journal are
+// posted again, in their original order, from a background goroutine.
+//
+// Parameters:
+//   - f: The function applied to each work item
+//   - journal: The journal recording posted and processed items; the caller remains responsible for closing it
+//   - backlog: The buffer size of the work and result channels
+//   - numWorkers: The number of workers running f concurrently
+//
+// Returns:
+//   - A pointer to a new DurableWorkerPool instance
+//   - An error if the pending items cannot be decoded
+//
+// Panics:
+//   - If backlog is negative or numWorkers is less than 1
+func NewDurableWorkerPool[W any, R any](f func(W) R, journal *Journal[W], backlog int, numWorkers int) (*DurableWorkerPool[W, R], error) {
+	entries, err := journal.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	dp := &DurableWorkerPool[W, R]{journal: journal, replaying: true}
+	dp.pool = NewWorkerPool(func(e JournalEntry[W]) R {
+		r := f(e.Item)
+		if err := journal.Ack(e.ID); err != nil {
+			dp.setErr(err)
+		}
+		return r
+	}, backlog, numWorkers)
+
+	go func() {
+		for _, e := range entries {
+			dp.pool.Post(e)
+		}
+		dp.mu.Lock()
+		dp.replaying = false
+		closing := dp.closing
+		dp.mu.Unlock()
+		if closing {
+			dp.pool.Close()
+		}
+	}()
+	return dp, nil
+}
+
+// setErr records the first journal error.
+func (dp *DurableWorkerPool[W, R]) setErr(err error) {
+	dp.mu.Lock()
+	if dp.err == nil {
+		dp.err = err
+	}
+	dp.mu.Unlock()
+}
+
+// Post journals a work item and submits it for processing. This will block when the work channel is full.
+//
+// Returns:
+//   - An error if the item could not be written to the journal, in which case it is not queued
+func (dp *DurableWorkerPool[W, R]) Post(w W) error {
+	id, err := dp.journal.Append(w)
+	if err != nil {
+		return err
+	}
+	dp.pool.Post(JournalEntry[W]{ID: id, Item: w})
+	return nil
+}
+
+// Close signals that no more work items will be posted. Like WorkerPool.Close it does not block: if replayed items
+// are still being queued, the underlying WorkerPool is closed once the last of them has been posted.
+func (dp *DurableWorkerPool[W, R]) Close() {
+	dp.mu.Lock()
+	if dp.replaying {
+		dp.closing = true
+		dp.mu.Unlock()
+		return
+	}
+	dp.mu.Unlock()
+	dp.pool.Close()
+}
+
+// Result retrieves and returns the next available result, decrementing the active work count.
+func (dp *DurableWorkerPool[W, R]) Result() R {
+	return dp.pool.Result()
+}
+
+// Next retrieves the next available result. The boolean is false when the pool has been closed and every result has
+// been consumed.
+func (dp *DurableWorkerPool[W, R]) Next() (R, bool) {
+	return dp.pool.Next()
+}
+
+// Len returns the current number of active work items, including replayed items already queued.
+func (dp *DurableWorkerPool[W, R]) Len() int32 {
+	return dp.pool.Len()
+}
+
+// IsActive checks if the pool has active work items.
+func (dp *DurableWorkerPool[W, R]) IsActive() bool {
+	return dp.pool.IsActive()
+}
+
+// Err returns the first error encountered while acknowledging processed items, or nil.
+func (dp *DurableWorkerPool[W, R]) Err() error {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	return dp.err
+}
@@ -0,0 +1,142 @@
+// Package util provides utility functions and types for common operations.
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrFutureCanceled is returned by Future.Get when the future was canceled before its work started.
+var ErrFutureCanceled = errors.New("future canceled")
+
+// future states
+const (
+	futurePending = iota
+	futureRunning
+	futureDone
+)
+
+// Future is a handle to the result of a single work item submitted to a WorkerPool. It completes exactly once, either
+// with the result of the work function or with ErrFutureCanceled.
+type Future[R any] struct {
+	done  chan struct{}
+	mu    sync.Mutex
+	state int
+	value R
+	err   error
+}
+
+// newFuture returns a pending Future.
+func newFuture[R any]() *Future[R] {
+	return &Future[R]{done: make(chan struct{})}
+}
+
+// start moves a pending future to running. It returns false if the future was canceled.
+func (f *Future[R]) start() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != futurePending {
+		return false
+	}
+	f.state = futureRunning
+	return true
+}
+
+// complete records the result and releases every waiter.
+func (f *Future[R]) complete(v R, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state == futureDone {
+		return
+	}
+	f.value, f.err, f.state = v, err, futureDone
+	close(f.done)
+}
+
+// Get blocks until the future completes or ctx is done.
+//
+// Returns:
+//   - The result of the work item
+//   - ErrFutureCanceled if the future was canceled, or ctx.Err() if ctx ended first
+func (f *Future[R]) Get(ctx context.Context) (R, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed when the future completes.
+func (f *Future[R]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Cancel prevents the work item from running if a worker has not picked it up yet. Work that has already started is
+// not interrupted.
+//
+// Returns:
+//   - true if the future was canceled, false if its work had already started or finished
+func (f *Future[R]) Cancel() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != futurePending {
+		return false
+	}
+	f.err, f.state = ErrFutureCanceled, futureDone
+	close(f.done)
+	return true
+}
+
+// AwaitAll waits for every future and returns their results in the same order.
+//
+// Returns:
+//   - The results of all futures
+//   - The first error encountered, in which case the results are incomplete
+func AwaitAll[R any](ctx context.Context, futures ...*Future[R]) ([]R, error) {
+	results := make([]R, len(futures))
+	for i, f := range futures {
+		v, err := f.Get(ctx)
+		if err != nil {
+			return results, err
+		}
+		results[i] = v
+	}
+	return results, nil
+}
+
+// AwaitAny waits for the first future to complete.
+//
+// Returns:
+//   - The index of the completed future, or -1 if ctx ended first or no futures were given
+//   - Its result
+//   - Its error, or ctx.Err() if ctx ended first
+func AwaitAny[R any](ctx context.Context, futures ...*Future[R]) (int, R, error) {
+	var zero R
+	if len(futures) == 0 {
+		return -1, zero, nil
+	}
+
+	first := make(chan int, len(futures))
+	stop := make(chan struct{})
+	defer close(stop)
+	for i, f := range futures {
+		go func() {
+			select {
+			case <-f.done:
+				first <- i
+			case <-stop:
+			}
+		}()
+	}
+
+	select {
+	case i := <-first:
+		v, err := futures[i].Get(ctx)
+		return i, v, err
+	case <-ctx.Done():
+		return -1, zero, ctx.Err()
+	}
+}
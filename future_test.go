@@ -0,0 +1,94 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolSubmit(t *testing.T) {
+	wp := NewWorkerPool(func(v int) int { return v * v }, 4, 2)
+	defer wp.Close()
+
+	futures := make([]*Future[int], 5)
+	for i := range futures {
+		futures[i] = wp.Submit(i + 1)
+	}
+
+	results, err := AwaitAll(context.Background(), futures...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if r != (i+1)*(i+1) {
+			t.Fatalf("future %d: expected %d, got %d", i, (i+1)*(i+1), r)
+		}
+	}
+
+	// futures and the shared result channel don't interfere
+	wp.Post(7)
+	f := wp.Submit(8)
+	if v := wp.Result(); v != 49 {
+		t.Fatalf("expected 49 on the result channel, got %d", v)
+	}
+	if v, _ := f.Get(context.Background()); v != 64 {
+		t.Fatalf("expected 64 from the future, got %d", v)
+	}
+	if wp.IsActive() {
+		t.Fatal("expected pool to be idle")
+	}
+}
+
+func TestFutureCancel(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wp := NewWorkerPool(func(v int) int {
+		started <- struct{}{}
+		<-release
+		return v
+	}, 4, 1)
+	defer wp.Close()
+
+	running := wp.Submit(1)
+	queued := wp.Submit(2)
+	<-started // the worker has picked up the first item
+
+	if running.Cancel() {
+		t.Fatal("expected a running future not to be cancelable")
+	}
+	if !queued.Cancel() {
+		t.Fatal("expected a queued future to be cancelable")
+	}
+	if _, err := queued.Get(context.Background()); !errors.Is(err, ErrFutureCanceled) {
+		t.Fatalf("expected ErrFutureCanceled, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := running.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	close(release)
+	<-running.Done()
+	if v, err := running.Get(context.Background()); err != nil || v != 1 {
+		t.Fatalf("expected 1,nil got %v,%v", v, err)
+	}
+}
+
+func TestAwaitAny(t *testing.T) {
+	wp := NewWorkerPool(func(d time.Duration) time.Duration {
+		time.Sleep(d)
+		return d
+	}, 4, 4)
+	defer wp.Close()
+
+	slow := wp.Submit(200 * time.Millisecond)
+	fast := wp.Submit(time.Millisecond)
+
+	i, v, err := AwaitAny(context.Background(), slow, fast)
+	if err != nil || i != 1 || v != time.Millisecond {
+		t.Fatalf("expected the fast future, got %d,%v,%v", i, v, err)
+	}
+}
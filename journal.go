@@ -0,0 +1,466 @@
+// Package util provides utility functions and types for common operations.
+package util
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Codec converts values to and from the bytes stored in a Journal.
+type Codec[W any] interface {
+	Encode(w W) ([]byte, error)
+	Decode(data []byte) (W, error)
+}
+
+// JSONCodec is a Codec that stores values as JSON using encoding/json.
+type JSONCodec[W any] struct{}
+
+// Encode marshals w to JSON.
+func (JSONCodec[W]) Encode(w W) ([]byte, error) {
+	return json.Marshal(w)
+}
+
+// Decode unmarshals a value of type W from JSON.
+func (JSONCodec[W]) Decode(data []byte) (W, error) {
+	var w W
+	err := json.Unmarshal(data, &w)
+	return w, err
+}
+
+// SyncPolicy determines when a Journal forces its writes to stable storage with fsync.
+type SyncPolicy struct {
+	interval time.Duration // > 0 sync periodically, 0 sync every record, < 0 never sync
+}
+
+var (
+	// SyncAlways fsyncs after every record. It is the safest and slowest policy.
+	SyncAlways = SyncPolicy{}
+	// SyncNever leaves flushing to the operating system. Records survive a process crash but not a power failure.
+	SyncNever = SyncPolicy{interval: -1}
+)
+
+// SyncEvery returns a SyncPolicy that fsyncs from a background goroutine at the given interval when there are
+// unsynced records. It panics if d is not positive.
+func SyncEvery(d time.Duration) SyncPolicy {
+	if d <= 0 {
+		panic("sync interval must be greater than zero")
+	}
+	return SyncPolicy{interval: d}
+}
+
+// JournalEntry is an unacknowledged item recovered from a Journal.
+type JournalEntry[W any] struct {
+	ID   uint64
+	Item W
+}
+
+// record kinds stored in the journal file
+const (
+	journalPut byte = 1
+	journalAck byte = 2
+)
+
+// journalHeaderSize is the size of a record header: kind (1), id (8) and payload length (4). Every record is followed
+// by a CRC-32 of the header and payload.
+const journalHeaderSize = 1 + 8 + 4
+
+// defaultCompactEvery is the number of acknowledgements after which a Journal compacts itself.
+const defaultCompactEvery = 1024
+
+// defaultCompactInterval is how often a Journal compacts itself when acknowledgements have accumulated, however few.
+const defaultCompactInterval = time.Hour
+
+// Journal is an append-only, write-ahead log of work items and their acknowledgements. Appended items stay pending
+// until acknowledged; after a crash, OpenJournal recovers the pending items from the file. A torn record at the end of
+// the file, left by a crash in the middle of a write, is discarded.
+//
+// The file is compacted automatically, by rewriting it with only the pending items, every time the configured number
+// of acknowledgements has accumulated and, so that a journal receiving few acknowledgements does not grow without
+// bound, periodically whenever any have. Journal is safe for concurrent use.
+type Journal[W any] struct {
+	path            string
+	codec           Codec[W]
+	policy          SyncPolicy
+	mu              sync.Mutex
+	file            *os.File
+	pending         map[uint64][]byte
+	nextID          uint64
+	acks            int
+	compactEvery    int
+	compactInterval time.Duration
+	dirty           bool
+	clock           Clock
+	reconfig        chan struct{} // wakes the background goroutine after WithClock or WithCompactInterval
+	stop            chan struct{}
+	stopped         sync.WaitGroup
+}
+
+// OpenJournal opens or creates the journal file at path and recovers its pending items.
+//
+// Parameters:
+//   - path: The journal file
+//   - codec: The codec used to encode and decode items
+//   - policy: The fsync policy (SyncAlways, SyncNever or SyncEvery)
+//
+// Returns:
+//   - A pointer to the opened Journal
+//   - An error if the file cannot be opened, read or repaired
+func OpenJournal[W any](path string, codec Codec[W], policy SyncPolicy) (*Journal[W], error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal[W]{
+		path:            path,
+		codec:           codec,
+		policy:          policy,
+		file:            file,
+		pending:         make(map[uint64][]byte),
+		nextID:          1,
+		compactEvery:    defaultCompactEvery,
+		compactInterval: defaultCompactInterval,
+		clock:           SystemClock,
+		reconfig:        make(chan struct{}, 1),
+		stop:            make(chan struct{}),
+	}
+
+	if err := j.recover(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	j.stopped.Add(1)
+	go j.background()
+	return j, nil
+}
+
+// recover replays the journal file, rebuilding the pending set and truncating a torn tail.
+func (j *Journal[W]) recover() error {
+	data, err := io.ReadAll(j.file)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for offset+journalHeaderSize+4 <= len(data) {
+		kind := data[offset]
+		id := binary.BigEndian.Uint64(data[offset+1:])
+		size := int(binary.BigEndian.Uint32(data[offset+9:]))
+		end := offset + journalHeaderSize + size
+		if end+4 > len(data) || binary.BigEndian.Uint32(data[end:]) != crc32.ChecksumIEEE(data[offset:end]) {
+			break
+		}
+
+		switch kind {
+		case journalPut:
+			j.pending[id] = slices.Clone(data[offset+journalHeaderSize : end])
+		case journalAck:
+			delete(j.pending, id)
+		default:
+			return fmt.Errorf("journal %s: unknown record kind %d at offset %d", j.path, kind, offset)
+		}
+		j.nextID = max(j.nextID, id+1)
+		offset = end + 4
+	}
+
+	if offset < len(data) {
+		if err := j.file.Truncate(int64(offset)); err != nil {
+			return err
+		}
+	}
+	_, err = j.file.Seek(int64(offset), io.SeekStart)
+	return err
+}
+
+// WithCompactEvery sets the number of acknowledgements after which the journal compacts itself and returns the
+// journal for chaining. Values less than 1 disable automatic compaction.
+func (j *Journal[W]) WithCompactEvery(n int) *Journal[W] {
+	j.mu.Lock()
+	j.compactEvery = n
+	j.mu.Unlock()
+	return j
+}
+
+// WithCompactInterval sets how often the journal compacts itself if any acknowledgements have accumulated since the
+// last compaction, and returns the journal for chaining. The default is one hour; values less than or equal to zero
+// disable periodic compaction.
+func (j *Journal[W]) WithCompactInterval(d time.Duration) *Journal[W] {
+	j.mu.Lock()
+	j.compactInterval = d
+	j.mu.Unlock()
+	j.wake()
+	return j
+}
+
+// WithClock sets the Clock driving periodic compaction and the periodic fsyncs of the SyncEvery policy, and returns
+// the journal for chaining.
+func (j *Journal[W]) WithClock(clock Clock) *Journal[W] {
+	j.mu.Lock()
+	j.clock = clock
+	j.mu.Unlock()
+	j.wake()
+	return j
+}
+
+// wake signals the background goroutine to pick up changed configuration.
+func (j *Journal[W]) wake() {
+	select {
+	case j.reconfig <- struct{}{}:
+	default:
+	}
+}
+
+// Append encodes w and records it as pending.
+//
+// Returns:
+//   - The ID used to acknowledge the item
+//   - An error if encoding or writing fails
+func (j *Journal[W]) Append(w W) (uint64, error) {
+	payload, err := j.codec.Encode(w)
+	if err != nil {
+		return 0, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	id := j.nextID
+	if err := j.write(journalPut, id, payload); err != nil {
+		return 0, err
+	}
+	j.nextID++
+	j.pending[id] = payload
+	return id, nil
+}
+
+// Ack records that the item with the given ID has been processed. Acknowledging an unknown ID is a no-op.
+//
+// Returns:
+//   - An error if writing the acknowledgement or compacting the journal fails
+func (j *Journal[W]) Ack(id uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.pending[id]; !ok {
+		return nil
+	}
+	if err := j.write(journalAck, id, nil); err != nil {
+		return err
+	}
+	delete(j.pending, id)
+
+	j.acks++
+	if j.compactEvery > 0 && j.acks >= j.compactEvery {
+		return j.compact()
+	}
+	return nil
+}
+
+// write appends a single record to the file and applies the sync policy. The caller must hold the lock.
+func (j *Journal[W]) write(kind byte, id uint64, payload []byte) error {
+	if j.file == nil {
+		return os.ErrClosed
+	}
+	if _, err := j.file.Write(encodeJournalRecord(kind, id, payload)); err != nil {
+		return err
+	}
+	if j.policy.interval == 0 {
+		return j.file.Sync()
+	}
+	j.dirty = true
+	return nil
+}
+
+// encodeJournalRecord lays out a record as header, payload and checksum.
+func encodeJournalRecord(kind byte, id uint64, payload []byte) []byte {
+	rec := make([]byte, journalHeaderSize, journalHeaderSize+len(payload)+4)
+	rec[0] = kind
+	binary.BigEndian.PutUint64(rec[1:], id)
+	binary.BigEndian.PutUint32(rec[9:], uint32(len(payload)))
+	rec = append(rec, payload...)
+	return binary.BigEndian.AppendUint32(rec, crc32.ChecksumIEEE(rec))
+}
+
+// Pending decodes and returns the unacknowledged items in the order they were appended.
+//
+// Returns:
+//   - The pending entries
+//   - An error if an item cannot be decoded
+func (j *Journal[W]) Pending() ([]JournalEntry[W], error) {
+	j.mu.Lock()
+	ids := make([]uint64, 0, len(j.pending))
+	for id := range j.pending {
+		ids = append(ids, id)
+	}
+	payloads := make([][]byte, 0, len(ids))
+	slices.Sort(ids)
+	for _, id := range ids {
+		payloads = append(payloads, j.pending[id])
+	}
+	j.mu.Unlock()
+
+	entries := make([]JournalEntry[W], 0, len(ids))
+	for i, id := range ids {
+		w, err := j.codec.Decode(payloads[i])
+		if err != nil {
+			return nil, fmt.Errorf("journal %s: decoding entry %d: %w", j.path, id, err)
+		}
+		entries = append(entries, JournalEntry[W]{ID: id, Item: w})
+	}
+	return entries, nil
+}
+
+// Len returns the number of unacknowledged items.
+func (j *Journal[W]) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.pending)
+}
+
+// Compact rewrites the journal file so that it only contains the pending items. The new file is written next to the
+// old one and atomically renamed over it.
+func (j *Journal[W]) Compact() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.compact()
+}
+
+// compact implements Compact. The caller must hold the lock.
+func (j *Journal[W]) compact() error {
+	if j.file == nil {
+		return os.ErrClosed
+	}
+
+	ids := make([]uint64, 0, len(j.pending))
+	for id := range j.pending {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err = tmp.Write(encodeJournalRecord(journalPut, id, j.pending[id])); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, j.path)
+	}
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	// make the rename durable before dropping the old file
+	if dir, err := os.Open(filepath.Dir(j.path)); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+
+	_ = j.file.Close()
+	j.file = tmp
+	j.acks = 0
+	j.dirty = false
+	return nil
+}
+
+// background periodically fsyncs unsynced records, as configured by the SyncEvery policy, and compacts the journal
+// when acknowledgements have accumulated, until the journal is closed.
+func (j *Journal[W]) background() {
+	defer j.stopped.Done()
+	var syncTicker, compactTicker Ticker
+	stop := func(t Ticker) {
+		if t != nil {
+			t.Stop()
+		}
+	}
+	start := func() {
+		stop(syncTicker)
+		stop(compactTicker)
+		syncTicker, compactTicker = nil, nil
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if j.policy.interval > 0 {
+			syncTicker = j.clock.NewTicker(j.policy.interval)
+		}
+		if j.compactInterval > 0 {
+			compactTicker = j.clock.NewTicker(j.compactInterval)
+		}
+	}
+	start()
+	defer func() {
+		stop(syncTicker)
+		stop(compactTicker)
+	}()
+
+	for {
+		var syncC, compactC <-chan time.Time
+		if syncTicker != nil {
+			syncC = syncTicker.C()
+		}
+		if compactTicker != nil {
+			compactC = compactTicker.C()
+		}
+
+		select {
+		case <-j.stop:
+			return
+		case <-j.reconfig:
+			start()
+		case <-syncC:
+			j.mu.Lock()
+			if j.dirty && j.file != nil {
+				if j.file.Sync() == nil {
+					j.dirty = false
+				}
+			}
+			j.mu.Unlock()
+		case <-compactC:
+			j.mu.Lock()
+			if j.acks > 0 && j.file != nil {
+				_ = j.compact() // a failed compaction leaves the acknowledgements counted and is retried next time
+			}
+			j.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the background goroutine, flushes the file to stable storage unless the policy is SyncNever, and closes it.
+func (j *Journal[W]) Close() error {
+	j.mu.Lock()
+	if j.file == nil {
+		j.mu.Unlock()
+		return os.ErrClosed
+	}
+	close(j.stop)
+	j.mu.Unlock()
+	j.stopped.Wait()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var err error
+	if j.policy.interval >= 0 {
+		err = j.file.Sync()
+	}
+	err = errors.Join(err, j.file.Close())
+	j.file = nil
+	return err
+}
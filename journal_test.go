@@ -0,0 +1,260 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type journalJob struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+}
+
+func TestJournalRecoversPending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.journal")
+
+	j, err := OpenJournal(path, JSONCodec[journalJob]{}, SyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint64
+	for i := 1; i <= 5; i++ {
+		id, err := j.Append(journalJob{"job", i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := j.Ack(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Ack(ids[3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = OpenJournal(path, JSONCodec[journalJob]{}, SyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	entries, err := j.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{2, 3, 5}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d pending entries, got %d", len(want), len(entries))
+	}
+	for i, e := range entries {
+		if e.Item.N != want[i] {
+			t.Fatalf("entry %d: expected %d, got %d", i, want[i], e.Item.N)
+		}
+	}
+
+	// new IDs continue after the recovered ones
+	id, _ := j.Append(journalJob{"job", 6})
+	if id <= ids[4] {
+		t.Fatalf("expected id greater than %d, got %d", ids[4], id)
+	}
+}
+
+func TestJournalDiscardsTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.journal")
+
+	j, _ := OpenJournal(path, JSONCodec[int]{}, SyncAlways)
+	_, _ = j.Append(1)
+	_, _ = j.Append(2)
+	_ = j.Close()
+
+	// simulate a crash in the middle of writing a record
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.Write(encodeJournalRecord(journalPut, 3, []byte("3"))[:7])
+	_ = f.Close()
+
+	j, err := OpenJournal(path, JSONCodec[int]{}, SyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Len() != 2 {
+		t.Fatalf("expected 2 pending entries, got %d", j.Len())
+	}
+	if _, err := j.Append(4); err != nil {
+		t.Fatal(err)
+	}
+	_ = j.Close()
+
+	j, _ = OpenJournal(path, JSONCodec[int]{}, SyncAlways)
+	defer j.Close()
+	entries, _ := j.Pending()
+	if len(entries) != 3 || entries[2].Item != 4 {
+		t.Fatalf("expected records after the torn tail to survive, got %+v", entries)
+	}
+}
+
+func TestJournalCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.journal")
+
+	j, _ := OpenJournal(path, JSONCodec[int]{}, SyncEvery(time.Millisecond))
+	j.WithCompactEvery(10)
+	defer j.Close()
+
+	for i := 0; i < 10; i++ {
+		id, _ := j.Append(i)
+		if i == 9 {
+			break
+		}
+		_ = j.Ack(id)
+	}
+	fi, _ := os.Stat(path)
+	before := fi.Size()
+
+	id, _ := j.Append(10)
+	if err := j.Ack(id); err != nil { // tenth acknowledgement triggers compaction
+		t.Fatal(err)
+	}
+
+	fi, _ = os.Stat(path)
+	if fi.Size() >= before {
+		t.Fatalf("expected journal to shrink after compaction, %d >= %d", fi.Size(), before)
+	}
+	entries, _ := j.Pending()
+	if len(entries) != 1 || entries[0].Item != 9 {
+		t.Fatalf("expected only item 9 to remain pending, got %+v", entries)
+	}
+}
+
+func TestDurableWorkerPoolReplays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.journal")
+
+	// first run: nothing is processed before the "crash"
+	j, _ := OpenJournal(path, JSONCodec[int]{}, SyncAlways)
+	for i := 1; i <= 3; i++ {
+		_, _ = j.Append(i)
+	}
+	_ = j.Close()
+
+	// second run: the pending items are replayed and acknowledged
+	j, _ = OpenJournal(path, JSONCodec[int]{}, SyncAlways)
+	dp, err := NewDurableWorkerPool(func(v int) int { return v * 2 }, j, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dp.Post(4); err != nil {
+		t.Fatal(err)
+	}
+	dp.Close()
+
+	sum := 0
+	for v, ok := dp.Next(); ok; v, ok = dp.Next() {
+		sum += v
+	}
+	if sum != 20 {
+		t.Fatalf("expected 20, got %d", sum)
+	}
+	if dp.Err() != nil {
+		t.Fatal(dp.Err())
+	}
+	if j.Len() != 0 {
+		t.Fatalf("expected every item to be acknowledged, %d pending", j.Len())
+	}
+	_ = j.Close()
+}
+
+func TestDurableWorkerPoolCloseDuringReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.journal")
+
+	j, _ := OpenJournal(path, JSONCodec[int]{}, SyncNever)
+	for i := 1; i <= 20; i++ { // far more than the backlog and workers can hold before results are read
+		_, _ = j.Append(i)
+	}
+	_ = j.Close()
+
+	j, _ = OpenJournal(path, JSONCodec[int]{}, SyncNever)
+	defer j.Close()
+	dp, err := NewDurableWorkerPool(func(v int) int { return v }, j, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dp.Close() // must not wait for the replay, which needs results to be drained
+
+	sum := 0
+	for v, ok := dp.Next(); ok; v, ok = dp.Next() {
+		sum += v
+	}
+	if sum != 210 {
+		t.Fatalf("expected every replayed item to be processed, got a sum of %d", sum)
+	}
+	if j.Len() != 0 {
+		t.Fatalf("expected every item to be acknowledged, %d pending", j.Len())
+	}
+}
+
+func TestJournalSyncEveryClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	j, err := OpenJournal(filepath.Join(t.TempDir(), "work.journal"), JSONCodec[int]{}, SyncEvery(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	j.WithClock(clock)
+	clock.BlockUntil(2) // the sync and compaction tickers
+
+	if _, err := j.Append(1); err != nil {
+		t.Fatal(err)
+	}
+	dirty := func() bool {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return j.dirty
+	}
+	if !dirty() {
+		t.Fatal("expected the record to wait for the syncer")
+	}
+
+	clock.Advance(time.Minute)
+	for deadline := time.Now().Add(time.Second); dirty(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the syncer to fsync after the interval")
+		}
+	}
+}
+
+func TestJournalCompactInterval(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	path := filepath.Join(t.TempDir(), "work.journal")
+	j, err := OpenJournal(path, JSONCodec[int]{}, SyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	j.WithCompactEvery(0).WithCompactInterval(time.Minute).WithClock(clock)
+	clock.BlockUntil(1) // the compaction ticker
+
+	id, _ := j.Append(1)
+	_, _ = j.Append(2)
+	if err := j.Ack(id); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat(path)
+	before := fi.Size()
+
+	clock.Advance(time.Minute)
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if fi, _ = os.Stat(path); fi.Size() < before {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a single acknowledgement to be compacted after the interval")
+		}
+	}
+	entries, _ := j.Pending()
+	if len(entries) != 1 || entries[0].Item != 2 {
+		t.Fatalf("expected only item 2 to remain pending, got %+v", entries)
+	}
+}
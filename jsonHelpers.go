@@ -3,12 +3,20 @@ package util
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/exp/constraints"
 )
 
 // UnixTimeFromIntString is a custom type for unmarshaling JSON string values containing Unix timestamps
-// into Go time.Time objects. It implements the json.Unmarshaler interface.
+// into Go time.Time objects. It implements the json.Unmarshaler and json.Marshaler interfaces.
 type UnixTimeFromIntString time.Time
 
 // UnmarshalJSON implements the json.Unmarshaler interface for UnixTimeFromIntString.
@@ -35,6 +43,16 @@ func (ut *UnixTimeFromIntString) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface for UnixTimeFromIntString.
+// It emits the Unix timestamp (seconds since epoch) as a JSON string, the format UnmarshalJSON accepts.
+// Sub-second precision is dropped.
+//
+// Returns:
+//   - The JSON encoding, e.g. "1700000000", and a nil error
+func (ut UnixTimeFromIntString) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, strconv.FormatInt(time.Time(ut).Unix(), 10)), nil
+}
+
 // Value returns the underlying time.Time value from the UnixTimeFromIntString.
 //
 // Returns:
@@ -44,7 +62,7 @@ func (ut *UnixTimeFromIntString) Value() time.Time {
 }
 
 // UnixTimeFromInt is a custom type for unmarshaling JSON numeric values containing Unix timestamps
-// into Go time.Time objects. It implements the json.Unmarshaler interface.
+// into Go time.Time objects. It implements the json.Unmarshaler and json.Marshaler interfaces.
 type UnixTimeFromInt time.Time
 
 // UnmarshalJSON implements the json.Unmarshaler interface for UnixTimeFromInt.
@@ -66,6 +84,16 @@ func (ut *UnixTimeFromInt) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface for UnixTimeFromInt.
+// It emits the Unix timestamp (seconds since epoch) as a JSON number, the format UnmarshalJSON accepts.
+// Sub-second precision is dropped.
+//
+// Returns:
+//   - The JSON encoding, e.g. 1700000000, and a nil error
+func (ut UnixTimeFromInt) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, time.Time(ut).Unix(), 10), nil
+}
+
 // Value returns the underlying time.Time value from the UnixTimeFromInt.
 //
 // Returns:
@@ -75,7 +103,7 @@ func (ut *UnixTimeFromInt) Value() time.Time {
 }
 
 // Uint64FromString is a custom type for unmarshaling JSON string values containing
-// unsigned 64-bit integers into Go uint64 values. It implements the json.Unmarshaler interface.
+// unsigned 64-bit integers into Go uint64 values. It implements the json.Unmarshaler and json.Marshaler interfaces.
 type Uint64FromString uint64
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Uint64FromString.
@@ -101,6 +129,15 @@ func (ut *Uint64FromString) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface for Uint64FromString.
+// It emits the value as a JSON string of decimal digits, the format UnmarshalJSON accepts.
+//
+// Returns:
+//   - The JSON encoding, e.g. "18446744073709551615", and a nil error
+func (ut Uint64FromString) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, strconv.FormatUint(uint64(ut), 10)), nil
+}
+
 // Value returns the underlying uint64 value from the Uint64FromString.
 //
 // Returns:
@@ -108,3 +145,582 @@ func (ut *Uint64FromString) UnmarshalJSON(data []byte) error {
 func (ut *Uint64FromString) Value() uint64 {
 	return uint64(*ut)
 }
+
+// TimeUnit is the unit of a numeric timestamp read or written by FlexTime.
+type TimeUnit int
+
+const (
+	UnitAuto         TimeUnit = iota // detect the unit from the magnitude of the value
+	UnitSeconds                      // seconds since the Unix epoch
+	UnitMilliseconds                 // milliseconds since the Unix epoch
+	UnitMicroseconds                 // microseconds since the Unix epoch
+	UnitNanoseconds                  // nanoseconds since the Unix epoch
+)
+
+// nanos returns the number of nanoseconds in one unit.
+func (u TimeUnit) nanos() int64 {
+	switch u {
+	case UnitMilliseconds:
+		return int64(time.Millisecond)
+	case UnitMicroseconds:
+		return int64(time.Microsecond)
+	case UnitNanoseconds:
+		return 1
+	default:
+		return int64(time.Second)
+	}
+}
+
+// detectUnit guesses the unit of a timestamp from its magnitude. Values up to 1e11 are taken as seconds, which covers
+// the years 1966 to 5138 in seconds while excluding any recent time in a finer unit; each finer unit gets the next
+// factor of 1000.
+func detectUnit(whole int64) TimeUnit {
+	if whole < 0 {
+		whole = -whole
+	}
+	switch {
+	case whole < 1e11:
+		return UnitSeconds
+	case whole < 1e14:
+		return UnitMilliseconds
+	case whole < 1e17:
+		return UnitMicroseconds
+	default:
+		return UnitNanoseconds
+	}
+}
+
+// FlexFormat is the JSON representation FlexTime marshals to.
+type FlexFormat int
+
+const (
+	FlexAuto       FlexFormat = iota // the format and unit the value was unmarshaled from; RFC3339 otherwise
+	FlexUnix                         // a JSON number in the configured unit
+	FlexUnixString                   // a JSON string holding a number in the configured unit
+	FlexRFC3339                      // a JSON string in RFC 3339 format with nanoseconds as needed
+	FlexRFC1123                      // a JSON string in RFC 1123 format with a zone name
+	FlexRFC1123Z                     // a JSON string in RFC 1123 format with a numeric zone
+)
+
+// FlexTime is a timestamp that unmarshals from JSON numbers, numeric strings, RFC 3339 and RFC 1123 strings. Numeric
+// values may be seconds, milliseconds, microseconds or nanoseconds since the epoch, with an optional fraction; the
+// unit is detected by magnitude unless Unit is set. It replaces UnixTimeFromInt and UnixTimeFromIntString when the
+// wire format varies.
+//
+// Unit and Format are options: set them before unmarshaling, for example by initializing the enclosing struct, or
+// before marshaling. With the default FlexAuto format a value marshals back to the format and unit it was read from,
+// so round-trips are lossless. JSON null leaves the value unchanged.
+type FlexTime struct {
+	Time   time.Time
+	Unit   TimeUnit   // unit of numeric input and output; UnitAuto detects it on input and uses seconds on output
+	Format FlexFormat // output format
+
+	wireFormat FlexFormat // format the value was unmarshaled from; FlexAuto if none
+	wireUnit   TimeUnit   // unit the value was unmarshaled from, for numeric formats
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for FlexTime.
+//
+// Parameters:
+//   - data: The JSON data to unmarshal
+//
+// Returns:
+//   - An error if the data is neither a number nor a string in a supported format, nil otherwise
+func (ft *FlexTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	format, text := FlexUnix, string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		format = FlexUnixString
+	}
+	return ft.parse(text, format)
+}
+
+// parse sets the time from text, a number in the given numeric format or a string in any supported format.
+func (ft *FlexTime) parse(text string, format FlexFormat) error {
+	if t, unit, err := parseUnix(text, ft.Unit); err == nil {
+		ft.Time, ft.wireFormat, ft.wireUnit = t, format, unit
+		return nil
+	} else if format == FlexUnix {
+		return fmt.Errorf("FlexTime: invalid number %s: %w", text, err)
+	}
+
+	for _, layout := range []struct {
+		format FlexFormat
+		layout string
+	}{{FlexRFC3339, time.RFC3339Nano}, {FlexRFC1123, time.RFC1123}, {FlexRFC1123Z, time.RFC1123Z}} {
+		if t, err := time.Parse(layout.layout, text); err == nil {
+			ft.Time, ft.wireFormat, ft.wireUnit = t, layout.format, UnitAuto
+			return nil
+		}
+	}
+	return fmt.Errorf("FlexTime: unsupported timestamp %q", text)
+}
+
+// MarshalJSON implements the json.Marshaler interface for FlexTime, emitting Format.
+//
+// Returns:
+//   - The JSON encoding of the timestamp and a nil error
+func (ft FlexTime) MarshalJSON() ([]byte, error) {
+	format, unit := ft.Format, ft.Unit
+	if format == FlexAuto {
+		format = ft.wireFormat
+		if format == FlexAuto {
+			format = FlexRFC3339
+		}
+	}
+	if unit == UnitAuto {
+		unit = ft.wireUnit
+	}
+
+	switch format {
+	case FlexUnix:
+		return []byte(formatUnix(ft.Time, unit)), nil
+	case FlexUnixString:
+		return strconv.AppendQuote(nil, formatUnix(ft.Time, unit)), nil
+	case FlexRFC1123:
+		return strconv.AppendQuote(nil, ft.Time.Format(time.RFC1123)), nil
+	case FlexRFC1123Z:
+		return strconv.AppendQuote(nil, ft.Time.Format(time.RFC1123Z)), nil
+	default:
+		return strconv.AppendQuote(nil, ft.Time.Format(time.RFC3339Nano)), nil
+	}
+}
+
+// Value returns the underlying time.Time value from the FlexTime.
+//
+// Returns:
+//   - The time.Time value represented by this FlexTime
+func (ft *FlexTime) Value() time.Time {
+	return ft.Time
+}
+
+// parseUnix parses a decimal timestamp with an optional sign, fraction and exponent in the given unit, detecting the
+// unit from the magnitude if it is UnitAuto. Digits finer than a nanosecond are dropped.
+func parseUnix(text string, unit TimeUnit) (time.Time, TimeUnit, error) {
+	if strings.ContainsAny(text, "eE") { // normalize exponent notation to plain decimal
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return time.Time{}, unit, err
+		}
+		text = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	neg := strings.HasPrefix(text, "-")
+	digits := strings.TrimPrefix(text, "-")
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	if intPart == "" || !IsASCIIDigits(intPart) || (fracPart != "" && !IsASCIIDigits(fracPart)) {
+		return time.Time{}, unit, fmt.Errorf("not a decimal number: %q", text)
+	}
+	whole, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return time.Time{}, unit, err
+	}
+	if unit == UnitAuto {
+		unit = detectUnit(whole)
+	}
+
+	per := unit.nanos()
+	fracDigits := len(strconv.FormatInt(per, 10)) - 1 // digits of a unit that are still whole nanoseconds
+	fracPart = (fracPart + strings.Repeat("0", fracDigits))[:fracDigits]
+	var frac int64
+	if fracDigits > 0 {
+		frac, _ = strconv.ParseInt(fracPart, 10, 64)
+	}
+
+	perSecond := int64(time.Second) / per
+	sec, nsec := whole/perSecond, (whole%perSecond)*per+frac
+	if neg {
+		sec, nsec = -sec, -nsec
+	}
+	return time.Unix(sec, nsec), unit, nil
+}
+
+// formatUnix formats t as a decimal number of units since the epoch, with a fraction if t is not a whole number of
+// units. UnitAuto formats seconds.
+func formatUnix(t time.Time, unit TimeUnit) string {
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	sign := ""
+	if sec < 0 { // format the distance to the epoch and prefix the sign
+		sign = "-"
+		sec = -sec
+		if nsec > 0 {
+			sec--
+			nsec = int64(time.Second) - nsec
+		}
+	}
+
+	per := unit.nanos()
+	whole := sec*(int64(time.Second)/per) + nsec/per
+	s := sign + strconv.FormatInt(whole, 10)
+	if frac := nsec % per; frac != 0 {
+		fracDigits := len(strconv.FormatInt(per, 10)) - 1
+		s += "." + strings.TrimRight(fmt.Sprintf("%0*d", fracDigits, frac), "0")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+// NumberFromString is a number of type T that unmarshals from either a JSON number or a JSON string holding one, as
+// APIs sending 64-bit values as strings do. It generalizes Uint64FromString to any integer or float type and implements
+// the json.Unmarshaler and json.Marshaler interfaces. It marshals to the form it was unmarshaled from, a string by
+// default. JSON null leaves the value unchanged.
+type NumberFromString[T constraints.Integer | constraints.Float] struct {
+	value T
+	bare  bool // unmarshaled from a JSON number
+}
+
+// NewNumberFromString returns a NumberFromString holding v, which marshals as a JSON string.
+func NewNumberFromString[T constraints.Integer | constraints.Float](v T) NumberFromString[T] {
+	return NumberFromString[T]{value: v}
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for NumberFromString.
+//
+// Parameters:
+//   - data: The JSON data to unmarshal
+//
+// Returns:
+//   - An error naming T if the data is not a number or does not fit in T, nil otherwise
+func (n *NumberFromString[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	text, bare := string(data), true
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		bare = false
+	}
+
+	v, err := parseNumber[T](text)
+	if err != nil {
+		return err
+	}
+	n.value, n.bare = v, bare
+	return nil
+}
+
+// parseNumber parses a decimal number into T.
+func parseNumber[T constraints.Integer | constraints.Float](text string) (T, error) {
+	typ := reflect.TypeFor[T]()
+	var value T
+	var err error
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var v int64
+		if v, err = strconv.ParseInt(text, 10, typ.Bits()); err == nil {
+			value = T(v)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var v uint64
+		if v, err = strconv.ParseUint(text, 10, typ.Bits()); err == nil {
+			value = T(v)
+		}
+	default:
+		var v float64
+		if v, err = strconv.ParseFloat(text, typ.Bits()); err == nil {
+			value = T(v)
+		}
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return value, fmt.Errorf("NumberFromString: %s is out of range for %s", text, typ)
+	}
+	if err != nil {
+		return value, fmt.Errorf("NumberFromString: invalid %s %q", typ, text)
+	}
+	return value, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for NumberFromString.
+//
+// Returns:
+//   - The number as a JSON number if it was unmarshaled from one, as a JSON string otherwise, and a nil error
+func (n NumberFromString[T]) MarshalJSON() ([]byte, error) {
+	var text string
+	switch typ := reflect.TypeFor[T](); typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		text = strconv.FormatInt(int64(n.value), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		text = strconv.FormatUint(uint64(n.value), 10)
+	default:
+		f := float64(n.value)
+		if n.bare && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return nil, fmt.Errorf("NumberFromString: %v cannot be a JSON number", f)
+		}
+		text = strconv.FormatFloat(f, 'g', -1, typ.Bits())
+	}
+	if n.bare {
+		return []byte(text), nil
+	}
+	return strconv.AppendQuote(nil, text), nil
+}
+
+// Value returns the underlying number from the NumberFromString.
+//
+// Returns:
+//   - The value of type T represented by this NumberFromString
+func (n *NumberFromString[T]) Value() T {
+	return n.value
+}
+
+// BoolFromString is a boolean that unmarshals from a JSON boolean, the numbers 1 and 0, or a JSON string holding
+// true/false, 1/0, yes/no, y/n, on/off or t/f in any letter case. It implements the json.Unmarshaler and
+// json.Marshaler interfaces and marshals to the JSON string "true" or "false". JSON null leaves the value unchanged.
+type BoolFromString bool
+
+// UnmarshalJSON implements the json.Unmarshaler interface for BoolFromString.
+//
+// Parameters:
+//   - data: The JSON data to unmarshal
+//
+// Returns:
+//   - An error if the data is not a recognized boolean, nil otherwise
+func (b *BoolFromString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	v, err := parseBool(text)
+	if err != nil {
+		return err
+	}
+	*b = BoolFromString(v)
+	return nil
+}
+
+// parseBool parses the boolean words BoolFromString accepts.
+func parseBool(text string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "true", "1", "yes", "y", "on", "t":
+		return true, nil
+	case "false", "0", "no", "n", "off", "f":
+		return false, nil
+	default:
+		return false, fmt.Errorf("BoolFromString: invalid boolean %q", text)
+	}
+}
+
+// MarshalJSON implements the json.Marshaler interface for BoolFromString.
+//
+// Returns:
+//   - "true" or "false" as a JSON string and a nil error
+func (b BoolFromString) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, strconv.FormatBool(bool(b))), nil
+}
+
+// Value returns the underlying bool value from the BoolFromString.
+//
+// Returns:
+//   - The bool value represented by this BoolFromString
+func (b *BoolFromString) Value() bool {
+	return bool(*b)
+}
+
+// Duration is a custom type for unmarshaling durations given as Go duration strings ("1m30s"), ISO 8601 durations
+// ("PT5M", "P1DT12H") or seconds, as a JSON number or numeric string, into Go time.Duration values. It implements the
+// json.Unmarshaler and json.Marshaler interfaces and marshals to a Go duration string. JSON null leaves the value
+// unchanged.
+type Duration time.Duration
+
+// isoDuration matches the ISO 8601 durations that have a fixed length: weeks, days, hours, minutes and seconds, each
+// with an optional fraction. Years and months are matched only to report them.
+var isoDuration = regexp.MustCompile(`^([-+]?)P(?:(\d+(?:[.,]\d+)?)Y)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)W)?` +
+	`(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+// UnmarshalJSON implements the json.Unmarshaler interface for Duration.
+//
+// Parameters:
+//   - data: The JSON data to unmarshal
+//
+// Returns:
+//   - An error if the data is not a duration in a supported format or overflows time.Duration, nil otherwise
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+
+	v, err := parseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// parseDuration parses seconds, an ISO 8601 duration or a Go duration string.
+func parseDuration(text string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(text, 64); err == nil {
+		v, err := durationOf(seconds, time.Second)
+		if err != nil {
+			return 0, fmt.Errorf("Duration: %q: %w", text, err)
+		}
+		return v, nil
+	}
+	if strings.HasPrefix(strings.TrimLeft(text, "+-"), "P") {
+		v, err := parseISODuration(text)
+		if err != nil {
+			return 0, fmt.Errorf("Duration: %q: %w", text, err)
+		}
+		return v, nil
+	}
+	v, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("Duration: invalid duration %q", text)
+	}
+	return v, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for Duration.
+// It emits the duration as a JSON string in Go duration notation, such as "1m30s".
+//
+// Returns:
+//   - The JSON encoding and a nil error
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, time.Duration(d).String()), nil
+}
+
+// Value returns the underlying time.Duration value from the Duration.
+//
+// Returns:
+//   - The time.Duration value represented by this Duration
+func (d *Duration) Value() time.Duration {
+	return time.Duration(*d)
+}
+
+// parseISODuration parses an ISO 8601 duration of weeks, days, hours, minutes and seconds. A day is 24 hours.
+func parseISODuration(text string) (time.Duration, error) {
+	m := isoDuration.FindStringSubmatch(text)
+	if m == nil || text == "P" || strings.HasSuffix(text, "T") || strings.HasSuffix(text, "P") {
+		return 0, errors.New("invalid ISO 8601 duration")
+	}
+	if m[2] != "" || m[3] != "" {
+		return 0, errors.New("years and months have no fixed length")
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var total time.Duration
+	for i, unit := range units {
+		field := m[4+i]
+		if field == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.Replace(field, ",", ".", 1), 64)
+		if err != nil {
+			return 0, err
+		}
+		part, err := durationOf(f, unit)
+		if err != nil || total > math.MaxInt64-part {
+			return 0, errors.New("overflows time.Duration")
+		}
+		total += part
+	}
+	if m[1] == "-" {
+		total = -total
+	}
+	return total, nil
+}
+
+// durationOf converts n units to a duration, rounded to the nearest nanosecond.
+func durationOf(n float64, unit time.Duration) (time.Duration, error) {
+	v := math.Round(n * float64(unit))
+	if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+		return 0, errors.New("overflows time.Duration")
+	}
+	return time.Duration(v), nil
+}
+
+// Optional is a JSON field that tells apart three states, as PATCH-style APIs require: absent from the document, set
+// to null, and set to a value. It works with any type encoding/json handles, including the other helper types, e.g.
+// Optional[UnixTimeFromInt]. It implements the json.Unmarshaler and json.Marshaler interfaces; tag a field with
+// `json:",omitzero"` to omit it on marshal when it is absent, since IsZero reports absence.
+type Optional[T any] struct {
+	value T
+	set   bool // the field was present, as null or a value
+	null  bool // the field was null
+}
+
+// OptionalOf returns an Optional set to v.
+func OptionalOf[T any](v T) Optional[T] {
+	return Optional[T]{value: v, set: true}
+}
+
+// OptionalNull returns an Optional set to null.
+func OptionalNull[T any]() Optional[T] {
+	return Optional[T]{set: true, null: true}
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for Optional. encoding/json only calls it for fields present
+// in the document, so a field that is never unmarshaled stays absent.
+//
+// Parameters:
+//   - data: The JSON data to unmarshal
+//
+// Returns:
+//   - An error if the data cannot be unmarshaled into T, nil otherwise
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*o = OptionalNull[T]()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*o = OptionalOf(v)
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for Optional.
+//
+// Returns:
+//   - null if the Optional is null or absent, the encoding of its value otherwise, and any error encoding the value
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.set || o.null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// IsSet reports whether the field was present, either as null or as a value.
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// IsNull reports whether the field was present and null.
+func (o Optional[T]) IsNull() bool {
+	return o.null
+}
+
+// IsZero reports whether the field is absent. encoding/json uses it to omit fields tagged omitzero.
+func (o Optional[T]) IsZero() bool {
+	return !o.set
+}
+
+// Get returns the value of the field.
+//
+// Returns:
+//   - The value, or the zero value of T if the field is absent or null
+//   - true if the field was set to a value
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.set && !o.null
+}
@@ -0,0 +1,280 @@
+package util
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// The JSON helper types implement sql.Scanner, so they can be scanned from query results directly. Most of them
+// cannot implement driver.Valuer, because its Value method would clash with their existing Value accessors. They
+// implement DriverValuer instead; wrap them with SQLValue when passing them as query arguments. Optional implements
+// driver.Valuer itself and converts its value the same way.
+//
+// Column types:
+//   - UnixTimeFromIntString, UnixTimeFromInt and FlexTime: timestamp (time.Time)
+//   - Uint64FromString and NumberFromString: numeric (int64 or float64); unsigned values above math.MaxInt64 are text
+//   - BoolFromString: boolean
+//   - Duration: numeric nanoseconds (int64), the same as time.Duration; numeric text is read as nanoseconds too, and
+//     other text durations are accepted when scanning
+//
+// NULL can only be scanned into an Optional; the other types return an error for it.
+
+// DriverValuer is implemented by the JSON helper types in place of driver.Valuer.
+type DriverValuer interface {
+	DriverValue() (driver.Value, error)
+}
+
+// sqlValuer adapts a DriverValuer to driver.Valuer.
+type sqlValuer struct{ v DriverValuer }
+
+func (s sqlValuer) Value() (driver.Value, error) {
+	return s.v.DriverValue()
+}
+
+// SQLValue wraps a JSON helper value for use as a database/sql query argument.
+//
+// Example:
+//
+//	db.Exec("INSERT INTO events (at) VALUES (?)", util.SQLValue(e.At))
+func SQLValue(v DriverValuer) driver.Valuer {
+	return sqlValuer{v}
+}
+
+// scanTime converts a scanned timestamp column: a time.Time, Unix seconds as an integer, or text holding Unix
+// seconds or an RFC 3339 timestamp.
+func scanTime(src any, typ string) (time.Time, error) {
+	switch v := src.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.Unix(v, 0), nil
+	case []byte:
+		return scanTime(string(v), typ)
+	case string:
+		if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(sec, 0), nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("%s: cannot scan %q", typ, v)
+	}
+	return time.Time{}, scanError(src, typ)
+}
+
+// scanError reports a column value of an unsupported type, pointing to Optional for NULL.
+func scanError(src any, typ string) error {
+	if src == nil {
+		return fmt.Errorf("%s: cannot scan NULL; use Optional[%s]", typ, typ)
+	}
+	return fmt.Errorf("%s: cannot scan %T", typ, src)
+}
+
+// Scan implements the sql.Scanner interface for UnixTimeFromIntString.
+func (ut *UnixTimeFromIntString) Scan(src any) error {
+	t, err := scanTime(src, "UnixTimeFromIntString")
+	if err == nil {
+		*ut = UnixTimeFromIntString(t)
+	}
+	return err
+}
+
+// DriverValue returns the time as a time.Time for a timestamp column.
+func (ut UnixTimeFromIntString) DriverValue() (driver.Value, error) {
+	return time.Time(ut), nil
+}
+
+// Scan implements the sql.Scanner interface for UnixTimeFromInt.
+func (ut *UnixTimeFromInt) Scan(src any) error {
+	t, err := scanTime(src, "UnixTimeFromInt")
+	if err == nil {
+		*ut = UnixTimeFromInt(t)
+	}
+	return err
+}
+
+// DriverValue returns the time as a time.Time for a timestamp column.
+func (ut UnixTimeFromInt) DriverValue() (driver.Value, error) {
+	return time.Time(ut), nil
+}
+
+// Scan implements the sql.Scanner interface for FlexTime. Integer columns are read in Unit, detected by magnitude if
+// it is UnitAuto, and text columns in any format UnmarshalJSON accepts for strings.
+func (ft *FlexTime) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		ft.Time, ft.wireFormat, ft.wireUnit = v, FlexAuto, UnitAuto
+		return nil
+	case int64:
+		return ft.parse(strconv.FormatInt(v, 10), FlexUnix)
+	case []byte:
+		return ft.parse(string(v), FlexUnixString)
+	case string:
+		return ft.parse(v, FlexUnixString)
+	}
+	return scanError(src, "FlexTime")
+}
+
+// DriverValue returns the time as a time.Time for a timestamp column.
+func (ft FlexTime) DriverValue() (driver.Value, error) {
+	return ft.Time, nil
+}
+
+// scanNumber converts a scanned numeric column to T, checking its range.
+func scanNumber[T constraints.Integer | constraints.Float](src any, typ string) (T, error) {
+	switch v := src.(type) {
+	case int64:
+		return parseNumber[T](strconv.FormatInt(v, 10))
+	case float64:
+		if k := reflect.TypeFor[T]().Kind(); k != reflect.Float32 && k != reflect.Float64 {
+			if v != math.Trunc(v) {
+				return 0, fmt.Errorf("%s: %v is not an integer", typ, v)
+			}
+			return parseNumber[T](strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		return parseNumber[T](strconv.FormatFloat(v, 'g', -1, 64))
+	case []byte:
+		return parseNumber[T](string(v))
+	case string:
+		return parseNumber[T](v)
+	}
+	var zero T
+	return zero, scanError(src, typ)
+}
+
+// numberValue converts a number to a driver value: int64 or float64, or text for unsigned values above math.MaxInt64.
+func numberValue[T constraints.Integer | constraints.Float](v T) driver.Value {
+	switch reflect.TypeFor[T]().Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := uint64(v); u > math.MaxInt64 {
+			return strconv.FormatUint(u, 10)
+		}
+		return int64(v)
+	case reflect.Float32, reflect.Float64:
+		return float64(v)
+	default:
+		return int64(v)
+	}
+}
+
+// Scan implements the sql.Scanner interface for Uint64FromString.
+func (ut *Uint64FromString) Scan(src any) error {
+	v, err := scanNumber[uint64](src, "Uint64FromString")
+	if err == nil {
+		*ut = Uint64FromString(v)
+	}
+	return err
+}
+
+// DriverValue returns the value as an int64, or as text if it exceeds math.MaxInt64.
+func (ut Uint64FromString) DriverValue() (driver.Value, error) {
+	return numberValue(uint64(ut)), nil
+}
+
+// Scan implements the sql.Scanner interface for NumberFromString. Values that do not fit in T are an error naming T.
+func (n *NumberFromString[T]) Scan(src any) error {
+	v, err := scanNumber[T](src, "NumberFromString")
+	if err == nil {
+		n.value = v
+	}
+	return err
+}
+
+// DriverValue returns the value as an int64 or float64, or as text for unsigned values above math.MaxInt64.
+func (n NumberFromString[T]) DriverValue() (driver.Value, error) {
+	return numberValue(n.value), nil
+}
+
+// Scan implements the sql.Scanner interface for BoolFromString. Integer columns are true unless 0, and text columns
+// accept the words UnmarshalJSON accepts.
+func (b *BoolFromString) Scan(src any) error {
+	switch v := src.(type) {
+	case bool:
+		*b = BoolFromString(v)
+		return nil
+	case int64:
+		*b = v != 0
+		return nil
+	case []byte:
+		return b.Scan(string(v))
+	case string:
+		v2, err := parseBool(v)
+		if err == nil {
+			*b = BoolFromString(v2)
+		}
+		return err
+	}
+	return scanError(src, "BoolFromString")
+}
+
+// DriverValue returns the value as a bool for a boolean column.
+func (b BoolFromString) DriverValue() (driver.Value, error) {
+	return bool(b), nil
+}
+
+// Scan implements the sql.Scanner interface for Duration. Numbers hold nanoseconds, like time.Duration, whether the
+// column is an integer or text, as drivers return integer columns as either; other text may be any duration string
+// UnmarshalJSON accepts.
+func (d *Duration) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*d = Duration(v)
+		return nil
+	case []byte:
+		return d.Scan(string(v))
+	case string:
+		if _, err := strconv.ParseFloat(v, 64); err == nil { // a bare number, not a duration string
+			ns, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("Duration: %q is not a whole number of nanoseconds", v)
+			}
+			*d = Duration(ns)
+			return nil
+		}
+		v2, err := parseDuration(v)
+		if err == nil {
+			*d = Duration(v2)
+		}
+		return err
+	}
+	return scanError(src, "Duration")
+}
+
+// DriverValue returns the duration in nanoseconds as an int64.
+func (d Duration) DriverValue() (driver.Value, error) {
+	return int64(d), nil
+}
+
+// Scan implements the sql.Scanner interface for Optional. NULL makes the Optional null; any other value is scanned
+// into T, using its Scan method if it has one.
+func (o *Optional[T]) Scan(src any) error {
+	var n sql.Null[T]
+	if err := n.Scan(src); err != nil {
+		return err
+	}
+	if !n.Valid {
+		*o = OptionalNull[T]()
+	} else {
+		*o = OptionalOf(n.V)
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface for Optional. Absent and null Optionals are NULL; a value is converted
+// with its DriverValue method if it has one and like any other query argument otherwise.
+func (o Optional[T]) Value() (driver.Value, error) {
+	if !o.set || o.null {
+		return nil, nil
+	}
+	if v, ok := any(o.value).(DriverValuer); ok {
+		return v.DriverValue()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(o.value)
+}
@@ -0,0 +1,199 @@
+package util
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDriver is an in-memory database/sql driver with one table per DSN. "INSERT" statements append their arguments
+// as a row and "SELECT" statements return every row.
+type fakeDriver struct {
+	mu     sync.Mutex
+	tables map[string][][]driver.Value
+}
+
+var fakeDB = &fakeDriver{tables: make(map[string][][]driver.Value)}
+
+func init() {
+	sql.Register("utilfake", fakeDB)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{d: d, table: name}, nil
+}
+
+type fakeConn struct {
+	d     *fakeDriver
+	table string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c: c, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+type fakeStmt struct {
+	c     *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if !strings.HasPrefix(s.query, "INSERT") {
+		return nil, errors.New("unsupported statement")
+	}
+	s.c.d.mu.Lock()
+	defer s.c.d.mu.Unlock()
+	s.c.d.tables[s.c.table] = append(s.c.d.tables[s.c.table], append([]driver.Value(nil), args...))
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT") {
+		return nil, errors.New("unsupported statement")
+	}
+	s.c.d.mu.Lock()
+	defer s.c.d.mu.Unlock()
+	return &fakeRows{rows: s.c.d.tables[s.c.table]}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+	i    int
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
+
+func TestJSONHelpersSQLRoundTrip(t *testing.T) {
+	db, err := sql.Open("utilfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	at := time.Unix(1700000000, 0)
+	_, err = db.Exec("INSERT",
+		SQLValue(UnixTimeFromInt(at)),
+		SQLValue(UnixTimeFromIntString(at)),
+		SQLValue(FlexTime{Time: at.Add(time.Millisecond)}),
+		SQLValue(Uint64FromString(math.MaxUint64)),
+		SQLValue(NewNumberFromString[int32](-7)),
+		SQLValue(NewNumberFromString(2.5)),
+		SQLValue(BoolFromString(true)),
+		SQLValue(Duration(90*time.Second)),
+		OptionalOf(UnixTimeFromInt(at)),
+		OptionalNull[string](),
+		Optional[int]{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		a   UnixTimeFromInt
+		b   UnixTimeFromIntString
+		c   FlexTime
+		u   Uint64FromString
+		i   NumberFromString[int32]
+		f   NumberFromString[float64]
+		ok  BoolFromString
+		d   Duration
+		opt Optional[UnixTimeFromInt]
+		nul Optional[string]
+		abs Optional[int]
+	)
+	if err := db.QueryRow("SELECT").Scan(&a, &b, &c, &u, &i, &f, &ok, &d, &opt, &nul, &abs); err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.Value().Equal(at) || !b.Value().Equal(at) || !c.Value().Equal(at.Add(time.Millisecond)) {
+		t.Errorf("unexpected timestamps %v, %v, %v", a.Value(), b.Value(), c.Value())
+	}
+	if u.Value() != math.MaxUint64 || i.Value() != -7 || f.Value() != 2.5 || !ok.Value() || d.Value() != 90*time.Second {
+		t.Errorf("unexpected values %d, %d, %v, %v, %v", u.Value(), i.Value(), f.Value(), ok.Value(), d.Value())
+	}
+	if v, set := opt.Get(); !set || !v.Value().Equal(at) {
+		t.Errorf("expected the optional timestamp, got %+v", opt)
+	}
+	if !nul.IsNull() || !abs.IsNull() {
+		t.Errorf("expected NULL to scan as null Optionals, got %+v and %+v", nul, abs)
+	}
+}
+
+func TestJSONHelpersScanConversions(t *testing.T) {
+	var ts UnixTimeFromInt
+	if err := ts.Scan([]byte("2023-11-14T22:13:20Z")); err != nil || ts.Value().Unix() != 1700000000 {
+		t.Errorf("expected a text timestamp to scan, got %v, %v", ts.Value(), err)
+	}
+	if err := ts.Scan(int64(60)); err != nil || ts.Value().Unix() != 60 {
+		t.Errorf("expected Unix seconds to scan, got %v, %v", ts.Value(), err)
+	}
+	if err := ts.Scan(nil); err == nil || !strings.Contains(err.Error(), "Optional") {
+		t.Errorf("expected NULL to point to Optional, got %v", err)
+	}
+
+	var ft FlexTime
+	if err := ft.Scan(int64(1700000000123)); err != nil || ft.Value().UnixMilli() != 1700000000123 {
+		t.Errorf("expected milliseconds to be detected, got %v, %v", ft.Value(), err)
+	}
+
+	var small NumberFromString[uint8]
+	if err := small.Scan(int64(300)); err == nil || !strings.Contains(err.Error(), "uint8") {
+		t.Errorf("expected a range error naming uint8, got %v", err)
+	}
+	if err := small.Scan(float64(1.5)); err == nil {
+		t.Error("expected a fractional value to be rejected for an integer type")
+	}
+	var u Uint64FromString
+	if err := u.Scan("18446744073709551615"); err != nil || u.Value() != math.MaxUint64 {
+		t.Errorf("expected text to scan into the full uint64 range, got %d, %v", u.Value(), err)
+	}
+
+	var b BoolFromString
+	if err := b.Scan("yes"); err != nil || !b.Value() {
+		t.Errorf("expected yes to scan as true, got %v, %v", b.Value(), err)
+	}
+	if err := b.Scan(int64(0)); err != nil || b.Value() {
+		t.Errorf("expected 0 to scan as false, got %v, %v", b.Value(), err)
+	}
+
+	var d Duration
+	if err := d.Scan("PT1M"); err != nil || d.Value() != time.Minute {
+		t.Errorf("expected an ISO 8601 duration to scan, got %v, %v", d.Value(), err)
+	}
+	if err := d.Scan(int64(time.Second)); err != nil || d.Value() != time.Second {
+		t.Errorf("expected integer nanoseconds to scan, got %v, %v", d.Value(), err)
+	}
+	if err := d.Scan([]byte("5000000000")); err != nil || d.Value() != 5*time.Second {
+		t.Errorf("expected numeric text to scan as nanoseconds like an integer column, got %v, %v", d.Value(), err)
+	}
+	if err := d.Scan("1.5"); err == nil {
+		t.Errorf("expected fractional numeric text to be rejected, got %v", d.Value())
+	}
+}
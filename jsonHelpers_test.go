@@ -0,0 +1,439 @@
+package util
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// jsonHelpers is a struct using every JSON helper type, as an API payload would.
+type jsonHelpers struct {
+	When    UnixTimeFromIntString `json:"when"`
+	At      UnixTimeFromInt       `json:"at"`
+	Counter Uint64FromString      `json:"counter"`
+}
+
+// wireSeconds maps an arbitrary int64 to the range of Unix seconds that time.Time represents exactly.
+func wireSeconds(n int64) int64 {
+	const limit = 1 << 40 // about 35,000 years either side of the epoch
+	return n % limit
+}
+
+func TestJSONHelpersWireRoundTrip(t *testing.T) {
+	// wire -> struct -> wire reproduces the original document
+	property := func(when, at int64, counter uint64) bool {
+		when, at = wireSeconds(when), wireSeconds(at)
+		in := `{"when":"` + strconv.FormatInt(when, 10) + `","at":` + strconv.FormatInt(at, 10) +
+			`,"counter":"` + strconv.FormatUint(counter, 10) + `"}`
+
+		var v jsonHelpers
+		if err := json.Unmarshal([]byte(in), &v); err != nil {
+			t.Log(err)
+			return false
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return string(out) == in
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONHelpersValueRoundTrip(t *testing.T) {
+	// struct -> wire -> struct preserves the values, through pointers as well as values
+	property := func(when, at int64, counter uint64) bool {
+		in := jsonHelpers{
+			When:    UnixTimeFromIntString(time.Unix(wireSeconds(when), 0)),
+			At:      UnixTimeFromInt(time.Unix(wireSeconds(at), 0)),
+			Counter: Uint64FromString(counter),
+		}
+		data, err := json.Marshal(&in)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		var out jsonHelpers
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Log(err)
+			return false
+		}
+		return out.When.Value().Equal(in.When.Value()) && out.At.Value().Equal(in.At.Value()) &&
+			out.Counter.Value() == in.Counter.Value()
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONHelpersMarshalFormat(t *testing.T) {
+	v := jsonHelpers{
+		When:    UnixTimeFromIntString(time.Unix(1700000000, 0)),
+		At:      UnixTimeFromInt(time.Unix(-1, 0)),
+		Counter: Uint64FromString(18446744073709551615),
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"when":"1700000000","at":-1,"counter":"18446744073709551615"}`
+	if string(out) != want {
+		t.Fatalf("expected %s, got %s", want, out)
+	}
+}
+
+func TestFlexTimeUnmarshal(t *testing.T) {
+	want := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC) // 1700000000
+	tests := []struct {
+		in   string
+		unit TimeUnit
+		want time.Time
+	}{
+		{`1700000000`, UnitAuto, want},
+		{`"1700000000"`, UnitAuto, want},
+		{`1700000000123`, UnitAuto, want.Add(123 * time.Millisecond)},
+		{`"1700000000123456"`, UnitAuto, want.Add(123456 * time.Microsecond)},
+		{`1700000000123456789`, UnitAuto, want.Add(123456789)},
+		{`1700000000.25`, UnitAuto, want.Add(250 * time.Millisecond)},
+		{`1.7e9`, UnitAuto, want},
+		{`-1.5`, UnitAuto, time.Unix(-2, 5e8)},
+		{`1000`, UnitMilliseconds, time.Unix(1, 0)},
+		{`"2023-11-14T22:13:20Z"`, UnitAuto, want},
+		{`"2023-11-14T23:13:20.5+01:00"`, UnitAuto, want.Add(500 * time.Millisecond)},
+		{`"Tue, 14 Nov 2023 22:13:20 UTC"`, UnitAuto, want},
+		{`"Tue, 14 Nov 2023 17:13:20 -0500"`, UnitAuto, want},
+	}
+	for _, tt := range tests {
+		ft := FlexTime{Unit: tt.unit}
+		if err := json.Unmarshal([]byte(tt.in), &ft); err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if !ft.Value().Equal(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.in, tt.want, ft.Value())
+		}
+	}
+
+	for _, in := range []string{`"yesterday"`, `true`, `""`, `"12ab"`, `{}`} {
+		var ft FlexTime
+		if err := json.Unmarshal([]byte(in), &ft); err == nil {
+			t.Errorf("%s: expected an error, got %v", in, ft.Value())
+		}
+	}
+}
+
+func TestFlexTimeWireRoundTrip(t *testing.T) {
+	for _, in := range []string{
+		`1700000000`, `"1700000000"`, `1700000000123`, `"1700000000123456"`, `1700000000123456789`, `-1.5`,
+		`1700000000.25`, `"2023-11-14T23:13:20.5+01:00"`, `"Tue, 14 Nov 2023 22:13:20 GMT"`,
+		`"Tue, 14 Nov 2023 17:13:20 -0500"`,
+	} {
+		var ft FlexTime
+		if err := json.Unmarshal([]byte(in), &ft); err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		out, err := json.Marshal(ft)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != in {
+			t.Errorf("expected %s to marshal back unchanged, got %s", in, out)
+		}
+	}
+}
+
+func TestFlexTimeValueRoundTrip(t *testing.T) {
+	formats := []FlexFormat{FlexUnix, FlexUnixString, FlexRFC3339}
+	units := []TimeUnit{UnitSeconds, UnitMilliseconds, UnitMicroseconds, UnitNanoseconds}
+	// any time representable in every unit, written in any numeric unit or RFC 3339, reads back equal when the
+	// reader knows the unit
+	property := func(sec int32, nsec uint32, f, u uint8) bool {
+		in := FlexTime{Time: time.Unix(int64(sec), int64(nsec%1e9)), Format: formats[int(f)%len(formats)], Unit: units[int(u)%len(units)]}
+		data, err := json.Marshal(in)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		out := FlexTime{Unit: in.Unit}
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Log(err)
+			return false
+		}
+		if !out.Value().Equal(in.Value()) {
+			t.Logf("%s: expected %v, got %v", data, in.Value(), out.Value())
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlexTimeFormatOption(t *testing.T) {
+	ft := FlexTime{Time: time.Unix(1700000000, 0), Format: FlexUnix, Unit: UnitMilliseconds}
+	if out, _ := json.Marshal(ft); string(out) != `1700000000000` {
+		t.Fatalf("expected milliseconds, got %s", out)
+	}
+	ft = FlexTime{Time: time.Unix(1700000000, 0).UTC()}
+	if out, _ := json.Marshal(ft); string(out) != `"2023-11-14T22:13:20Z"` {
+		t.Fatalf("expected RFC 3339 by default, got %s", out)
+	}
+
+	var v struct {
+		At FlexTime `json:"at"`
+	}
+	v.At.Format = FlexRFC3339 // options set before unmarshaling survive it
+	if err := json.Unmarshal([]byte(`{"at":1700000000}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := json.Marshal(v)
+	if want := `{"at":"` + time.Unix(1700000000, 0).Format(time.RFC3339Nano) + `"}`; string(out) != want {
+		t.Fatalf("expected %s, got %s", want, out)
+	}
+}
+
+func TestNumberFromString(t *testing.T) {
+	var v struct {
+		ID    NumberFromString[int64]   `json:"id"`
+		Price NumberFromString[float64] `json:"price"`
+		Small NumberFromString[uint8]   `json:"small"`
+	}
+	in := `{"id":"-9223372036854775808","price":12.5,"small":"255"}`
+	if err := json.Unmarshal([]byte(in), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID.Value() != math.MinInt64 || v.Price.Value() != 12.5 || v.Small.Value() != 255 {
+		t.Fatalf("unexpected values %d, %v, %d", v.ID.Value(), v.Price.Value(), v.Small.Value())
+	}
+	if out, _ := json.Marshal(v); string(out) != in {
+		t.Fatalf("expected each number to keep its form, got %s", out)
+	}
+	if out, _ := json.Marshal(NewNumberFromString[float32](0.1)); string(out) != `"0.1"` {
+		t.Fatalf("expected a quoted shortest float32, got %s", out)
+	}
+
+	var small NumberFromString[uint8]
+	err := json.Unmarshal([]byte(`"256"`), &small)
+	if err == nil || !strings.Contains(err.Error(), "uint8") || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("expected a range error naming uint8, got %v", err)
+	}
+	var f NumberFromString[float32]
+	if err := json.Unmarshal([]byte(`1e39`), &f); err == nil || !strings.Contains(err.Error(), "float32") {
+		t.Fatalf("expected a range error naming float32, got %v", err)
+	}
+	var d NumberFromString[time.Duration]
+	if err := json.Unmarshal([]byte(`"1.5"`), &d); err == nil || !strings.Contains(err.Error(), "time.Duration") {
+		t.Fatalf("expected a syntax error naming time.Duration, got %v", err)
+	}
+}
+
+func TestNumberFromStringRoundTrip(t *testing.T) {
+	property := func(i int64, u uint64, f float64, quoted bool) bool {
+		wrap := func(s string) string {
+			if quoted {
+				return strconv.Quote(s)
+			}
+			return s
+		}
+		in := `{"i":` + wrap(strconv.FormatInt(i, 10)) + `,"u":` + wrap(strconv.FormatUint(u, 10)) +
+			`,"f":` + wrap(strconv.FormatFloat(f, 'g', -1, 64)) + `}`
+		var v struct {
+			I NumberFromString[int64]   `json:"i"`
+			U NumberFromString[uint64]  `json:"u"`
+			F NumberFromString[float64] `json:"f"`
+		}
+		if err := json.Unmarshal([]byte(in), &v); err != nil {
+			t.Log(err)
+			return false
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return string(out) == in && v.I.Value() == i && v.U.Value() == u && v.F.Value() == f
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBoolFromString(t *testing.T) {
+	for in, want := range map[string]bool{
+		`true`: true, `false`: false, `1`: true, `0`: false, `"true"`: true, `"FALSE"`: false, `"1"`: true,
+		`"0"`: false, `"yes"`: true, `"No"`: false, `"on"`: true, `"off"`: false, `"y"`: true, `"f"`: false,
+	} {
+		b := BoolFromString(!want)
+		if err := json.Unmarshal([]byte(in), &b); err != nil || b.Value() != want {
+			t.Errorf("%s: expected %v, got %v, %v", in, want, b.Value(), err)
+		}
+	}
+	for _, in := range []string{`"maybe"`, `2`, `""`, `{}`} {
+		var b BoolFromString
+		if err := json.Unmarshal([]byte(in), &b); err == nil {
+			t.Errorf("%s: expected an error", in)
+		}
+	}
+
+	property := func(v bool) bool {
+		data, err := json.Marshal(BoolFromString(v))
+		if err != nil {
+			return false
+		}
+		var b BoolFromString
+		return json.Unmarshal(data, &b) == nil && b.Value() == v
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		`"1m30s"`:          90 * time.Second,
+		`"250ms"`:          250 * time.Millisecond,
+		`"-1.5h"`:          -90 * time.Minute,
+		`90`:               90 * time.Second,
+		`1.5`:              1500 * time.Millisecond,
+		`"30"`:             30 * time.Second,
+		`"PT5M"`:           5 * time.Minute,
+		`"P1DT12H"`:        36 * time.Hour,
+		`"P2W"`:            14 * 24 * time.Hour,
+		`"PT1H2M3.5S"`:     time.Hour + 2*time.Minute + 3500*time.Millisecond,
+		`"PT0,25S"`:        250 * time.Millisecond,
+		`"-PT10S"`:         -10 * time.Second,
+		`"PT0.000000001S"`: time.Nanosecond,
+	}
+	for in, want := range tests {
+		var d Duration
+		if err := json.Unmarshal([]byte(in), &d); err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if d.Value() != want {
+			t.Errorf("%s: expected %v, got %v", in, want, d.Value())
+		}
+	}
+
+	for _, in := range []string{`"P1Y"`, `"P1M"`, `"P"`, `"PT"`, `"PT5X"`, `"5 minutes"`, `"1e300"`, `"PT1e3S"`, `true`} {
+		var d Duration
+		if err := json.Unmarshal([]byte(in), &d); err == nil {
+			t.Errorf("%s: expected an error, got %v", in, d.Value())
+		}
+	}
+
+	if out, _ := json.Marshal(Duration(90 * time.Second)); string(out) != `"1m30s"` {
+		t.Fatalf("expected Go duration notation, got %s", out)
+	}
+}
+
+func TestDurationRoundTrip(t *testing.T) {
+	property := func(n int64) bool {
+		in := Duration(n)
+		data, err := json.Marshal(in)
+		if err != nil {
+			return false
+		}
+		var out Duration
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Log(err)
+			return false
+		}
+		return out == in
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// patch is a PATCH-style request body.
+type patch struct {
+	Name    Optional[string]          `json:"name,omitzero"`
+	Expires Optional[UnixTimeFromInt] `json:"expires,omitzero"`
+	Limit   Optional[*int]            `json:"limit,omitzero"`
+}
+
+func TestOptional(t *testing.T) {
+	var p patch
+	if err := json.Unmarshal([]byte(`{"name":"x","expires":null}`), &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := p.Name.Get(); !ok || v != "x" || !p.Name.IsSet() || p.Name.IsNull() {
+		t.Fatalf("expected name to be set to x, got %+v", p.Name)
+	}
+	if _, ok := p.Expires.Get(); ok || !p.Expires.IsSet() || !p.Expires.IsNull() {
+		t.Fatalf("expected expires to be null, got %+v", p.Expires)
+	}
+	if _, ok := p.Limit.Get(); ok || p.Limit.IsSet() || p.Limit.IsNull() {
+		t.Fatalf("expected limit to be absent, got %+v", p.Limit)
+	}
+
+	out, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"name":"x","expires":null}` {
+		t.Fatalf("expected absent fields to be omitted, got %s", out)
+	}
+}
+
+func TestOptionalComposesWithHelpers(t *testing.T) {
+	var p patch
+	if err := json.Unmarshal([]byte(`{"expires":1700000000,"limit":5}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	exp, ok := p.Expires.Get()
+	if !ok || exp.Value().Unix() != 1700000000 {
+		t.Fatalf("expected the helper type to decode inside Optional, got %+v", p.Expires)
+	}
+	if l, ok := p.Limit.Get(); !ok || *l != 5 {
+		t.Fatalf("expected limit 5, got %+v", p.Limit)
+	}
+
+	if err := json.Unmarshal([]byte(`{"expires":"soon"}`), &p); err == nil {
+		t.Fatal("expected the helper type's error to surface")
+	}
+
+	q := patch{Name: OptionalNull[string](), Expires: OptionalOf(UnixTimeFromInt(time.Unix(60, 0)))}
+	if out, _ := json.Marshal(q); string(out) != `{"name":null,"expires":60}` {
+		t.Fatalf("unexpected encoding %s", out)
+	}
+}
+
+func TestOptionalRoundTrip(t *testing.T) {
+	// absent, null and set survive marshal and unmarshal
+	property := func(state uint8, v int64) bool {
+		var in Optional[int64]
+		switch state % 3 {
+		case 1:
+			in = OptionalNull[int64]()
+		case 2:
+			in = OptionalOf(v)
+		}
+		data, err := json.Marshal(struct {
+			F Optional[int64] `json:"f,omitzero"`
+		}{in})
+		if err != nil {
+			return false
+		}
+		var out struct {
+			F Optional[int64] `json:"f,omitzero"`
+		}
+		if err := json.Unmarshal(data, &out); err != nil {
+			return false
+		}
+		return out.F == in
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,176 @@
+package util
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+// KeyedWorkerPool is a generic worker pool that guarantees serial, in-order execution of work items sharing the same key
+// while items with different keys are processed in parallel. Each key is hashed to a lane, and every lane is served
+// by exactly one worker, so items for a key run in the order they were posted and their results are delivered in
+// that order too.
+//
+// Optionally the pool can rebalance: when the lane a key hashes to is hot, an idle key (one with no queued or running
+// items) is assigned to the least loaded lane instead. A key only moves while it is idle, so per-key ordering holds.
+type KeyedWorkerPool[K comparable, W any, R any] struct {
+	lanes   []chan W
+	result  chan R
+	f       func(W) R
+	key     func(W) K
+	seed    maphash.Seed
+	mu      sync.Mutex
+	active  map[K]*keyAssignment // keys with queued or running items
+	load    []int                // queued or running items per lane
+	hot     int                  // rebalance threshold; 0 disables rebalancing
+	count   atomic.Int32
+	workers sync.WaitGroup
+}
+
+// keyAssignment records the lane a busy key is pinned to and how many of its items are outstanding.
+type keyAssignment struct {
+	lane    int
+	pending int
+}
+
+// NewKeyedWorkerPool creates and initializes a new KeyedWorkerPool.
+//
+// Parameters:
+//   - key: A function returning the ordering key of a work item; it must return the same key for the same item
+//   - f: The function applied to each work item
+//   - backlog: The buffer size of each lane and of the result channel
+//   - numLanes: The number of lanes, which is also the maximum parallelism
+//
+// Returns:
+//   - A pointer to a new KeyedWorkerPool instance
+//
+// Panics:
+//   - If backlog is negative or numLanes is less than 1
+func NewKeyedWorkerPool[K comparable, W any, R any](key func(W) K, f func(W) R, backlog int, numLanes int) *KeyedWorkerPool[K, W, R] {
+	if backlog < 0 {
+		panic("backlog must be greater than -1")
+	}
+	if numLanes < 1 {
+		panic("numLanes must be greater than zero")
+	}
+	pool := &KeyedWorkerPool[K, W, R]{
+		lanes:  make([]chan W, numLanes),
+		result: make(chan R, backlog),
+		f:      f,
+		key:    key,
+		seed:   maphash.MakeSeed(),
+		active: make(map[K]*keyAssignment),
+		load:   make([]int, numLanes),
+	}
+
+	// start one worker per lane
+	pool.workers.Add(numLanes)
+	for i := range pool.lanes {
+		pool.lanes[i] = make(chan W, backlog)
+		go func(lane int) {
+			defer pool.workers.Done()
+			for w := range pool.lanes[lane] { // until closed
+				pool.result <- pool.f(w)
+				pool.release(pool.key(w), lane)
+			}
+		}(i)
+	}
+
+	go func() {
+		pool.workers.Wait()
+		close(pool.result)
+	}()
+	return pool
+}
+
+// WithRebalancing enables rebalancing of idle keys away from hot lanes and returns the pool for chaining.
+// A lane is hot when it holds at least hotThreshold queued or running items. It should be called before the first Post.
+//
+// Parameters:
+//   - hotThreshold: The lane load at which idle keys are steered to the least loaded lane; 0 disables rebalancing
+func (kp *KeyedWorkerPool[K, W, R]) WithRebalancing(hotThreshold int) *KeyedWorkerPool[K, W, R] {
+	kp.mu.Lock()
+	kp.hot = max(hotThreshold, 0)
+	kp.mu.Unlock()
+	return kp
+}
+
+// Post submits a work item to the lane owning its key and increments the active work count.
+// This will block when the lane is full.
+func (kp *KeyedWorkerPool[K, W, R]) Post(w W) {
+	lane := kp.assign(kp.key(w))
+	kp.count.Add(1)
+	kp.lanes[lane] <- w
+}
+
+// assign returns the lane for key k, pinning the key to it until its outstanding items are done.
+func (kp *KeyedWorkerPool[K, W, R]) assign(k K) int {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	a, ok := kp.active[k]
+	if !ok {
+		lane := int(maphash.Comparable(kp.seed, k) % uint64(len(kp.lanes)))
+		if kp.hot > 0 && kp.load[lane] >= kp.hot {
+			for i, l := range kp.load {
+				if l < kp.load[lane] {
+					lane = i
+				}
+			}
+		}
+		a = &keyAssignment{lane: lane}
+		kp.active[k] = a
+	}
+	a.pending++
+	kp.load[a.lane]++
+	return a.lane
+}
+
+// release marks one item of key k on lane as done, unpinning the key when it becomes idle.
+func (kp *KeyedWorkerPool[K, W, R]) release(k K, lane int) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	kp.load[lane]--
+	if a, ok := kp.active[k]; ok {
+		a.pending--
+		if a.pending == 0 {
+			delete(kp.active, k)
+		}
+	}
+}
+
+// Close terminates every lane, signaling that no more work items will be submitted to the KeyedWorkerPool.
+// Once the lanes have drained, Next reports false.
+func (kp *KeyedWorkerPool[K, W, R]) Close() {
+	for _, lane := range kp.lanes {
+		close(lane)
+	}
+}
+
+// Result retrieves and returns the next available result, decrementing the active work count.
+// After Close, once all results have been consumed, Result returns the zero value of R.
+func (kp *KeyedWorkerPool[K, W, R]) Result() R {
+	v, _ := kp.Next()
+	return v
+}
+
+// Next retrieves the next available result, decrementing the active work count.
+// The boolean is false when the pool has been closed and every result has been consumed.
+func (kp *KeyedWorkerPool[K, W, R]) Next() (R, bool) {
+	v, ok := <-kp.result
+	if ok {
+		kp.count.Add(-1)
+	}
+	return v, ok
+}
+
+// Len returns the current number of active work items in the KeyedWorkerPool.
+func (kp *KeyedWorkerPool[K, W, R]) Len() int32 {
+	return kp.count.Load()
+}
+
+// IsActive checks if the KeyedWorkerPool has active work items.
+func (kp *KeyedWorkerPool[K, W, R]) IsActive() bool {
+	return kp.count.Load() > 0
+}
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type keyedItem struct {
+	account string
+	seq     int
+}
+
+func TestKeyedWorkerPoolPerKeyOrder(t *testing.T) {
+	kp := NewKeyedWorkerPool(func(i keyedItem) string { return i.account }, func(i keyedItem) keyedItem {
+		time.Sleep(time.Millisecond)
+		return i
+	}, 4, 4)
+
+	accounts := []string{"a", "b", "c", "d", "e"}
+	go func() {
+		for seq := 0; seq < 20; seq++ {
+			for _, a := range accounts {
+				kp.Post(keyedItem{a, seq})
+			}
+		}
+		kp.Close()
+	}()
+
+	next := map[string]int{}
+	for {
+		r, ok := kp.Next()
+		if !ok {
+			break
+		}
+		if r.seq != next[r.account] {
+			t.Fatalf("account %s: expected seq %d, got %d", r.account, next[r.account], r.seq)
+		}
+		next[r.account]++
+	}
+
+	for _, a := range accounts {
+		if next[a] != 20 {
+			t.Fatalf("account %s: expected 20 results, got %d", a, next[a])
+		}
+	}
+	if kp.IsActive() {
+		t.Fatal("expected pool to be idle")
+	}
+}
+
+func TestKeyedWorkerPoolParallelAcrossKeys(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	kp := NewKeyedWorkerPool(func(k int) int { return k }, func(k int) int {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return k
+	}, 8, 8).WithRebalancing(1)
+
+	for k := 0; k < 8; k++ {
+		kp.Post(k)
+	}
+	kp.Close()
+	for _, ok := kp.Next(); ok; _, ok = kp.Next() {
+	}
+
+	if peak < 2 {
+		t.Fatalf("expected distinct keys to run in parallel, peak concurrency %d", peak)
+	}
+}
+
+func TestKeyedWorkerPoolRebalance(t *testing.T) {
+	kp := NewKeyedWorkerPool(func(k int) int { return k }, func(k int) int { return k }, 4, 4).WithRebalancing(1)
+
+	// pin key 1 to its lane, then every other idle key must avoid that hot lane
+	block := kp.assign(1)
+	for k := 2; k < 50; k++ {
+		if lane := kp.assign(k); lane == block {
+			t.Fatalf("key %d was assigned to hot lane %d", k, lane)
+		}
+		kp.release(k, kp.active[k].lane)
+	}
+	kp.release(1, block)
+	if len(kp.active) != 0 {
+		t.Fatalf("expected no pinned keys, got %d", len(kp.active))
+	}
+	kp.Close()
+}
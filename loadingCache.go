@@ -0,0 +1,336 @@
+package util
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats is a snapshot of the counters of a LoadingCache.
+type CacheStats struct {
+	Hits          uint64 // lookups served from the cache
+	Misses        uint64 // lookups that required a load
+	LoadSuccesses uint64 // loader calls that returned a value
+	LoadErrors    uint64 // loader calls that returned an error
+	Evictions     uint64 // entries removed to stay within the maximum size
+}
+
+// HitRate returns the fraction of lookups served from the cache, or 0 if there were none.
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// cacheEntry is an element of the LRU list of a LoadingCache.
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// LoadingCache is a keyed counterpart of Debouncer: it caches values per key for a TTL and loads missing or expired
+// values with a loader function. Concurrent lookups of the same missing key share a single loader call. When the
+// cache holds maxSize entries, adding another evicts the least recently used one. Errors are not cached. It is safe
+// for concurrent use.
+type LoadingCache[K comparable, V any] struct {
+	loader     func(ctx context.Context, key K) (V, error)
+	bulkLoader func(ctx context.Context, keys []K) (map[K]V, error)
+	ttl        time.Duration
+	ttlFunc    func(K, V) time.Duration
+	timeout    time.Duration // per-load timeout; 0 means none
+	maxSize    int
+	mu         sync.Mutex
+	entries    map[K]*list.Element
+	lru        *list.List // front is the most recently used entry
+	flight     singleflight[K, V]
+	clock      Clock
+
+	hits, misses, loadSuccesses, loadErrors, evictions atomic.Uint64
+}
+
+// NewLoadingCache creates and initializes a new LoadingCache.
+//
+// Parameters:
+//   - loader: The function loading the value of a key; it should honor ctx cancellation
+//   - ttl: How long a loaded value is served before it is loaded again
+//   - maxSize: The maximum number of cached entries
+//
+// Returns:
+//   - A pointer to a new LoadingCache instance
+//
+// Panics:
+//   - If ttl is not positive or maxSize is less than 1
+func NewLoadingCache[K comparable, V any](loader func(ctx context.Context, key K) (V, error), ttl time.Duration, maxSize int) *LoadingCache[K, V] {
+	if ttl <= 0 {
+		panic("ttl must be greater than zero")
+	}
+	if maxSize < 1 {
+		panic("maxSize must be greater than zero")
+	}
+	return &LoadingCache[K, V]{
+		loader:  loader,
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[K]*list.Element),
+		lru:     list.New(),
+		clock:   SystemClock,
+	}
+}
+
+// WithClock sets the Clock used for entry expiry and returns the cache for chaining. It should be called before the
+// cache is used.
+func (c *LoadingCache[K, V]) WithClock(clock Clock) *LoadingCache[K, V] {
+	c.mu.Lock()
+	c.clock = clock
+	c.mu.Unlock()
+	return c
+}
+
+// WithBulkLoader sets a function that GetAll uses to load all missing keys in one call and returns the cache for
+// chaining. Keys missing from the returned map are treated as not found. It should be called before the cache is used.
+func (c *LoadingCache[K, V]) WithBulkLoader(f func(ctx context.Context, keys []K) (map[K]V, error)) *LoadingCache[K, V] {
+	c.bulkLoader = f
+	return c
+}
+
+// WithTTLFunc sets a function choosing the TTL of each loaded value, for example from an expiry time the value
+// carries, and returns the cache for chaining. It applies to values from the loader and the bulk loader; a
+// non-positive result falls back to the cache's TTL. It should be called before the cache is used.
+func (c *LoadingCache[K, V]) WithTTLFunc(f func(key K, value V) time.Duration) *LoadingCache[K, V] {
+	c.ttlFunc = f
+	return c
+}
+
+// WithLoadTimeout limits each loader and bulk loader call to timeout and returns the cache for chaining. A load
+// exceeding it sees its context canceled. 0, the default, disables the limit. It should be called before the cache is
+// used.
+func (c *LoadingCache[K, V]) WithLoadTimeout(timeout time.Duration) *LoadingCache[K, V] {
+	c.timeout = timeout
+	return c
+}
+
+// Get returns the cached value of key, loading it if it is missing or expired. Concurrent callers for the same key
+// wait for a single load. As the load is shared, it is not canceled with the context of the caller that started it,
+// whose values it keeps; it is only limited by WithLoadTimeout. A caller whose ctx is done stops waiting and returns
+// ctx.Err(), leaving the load to complete for the others. A panicking loader fails the load with an error describing
+// the panic.
+//
+// Returns:
+//   - The value of key
+//   - The loader error, which is not cached, or ctx.Err()
+func (c *LoadingCache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	if v, ok := c.lookup(key); ok {
+		c.hits.Add(1)
+		return v, nil
+	}
+	c.misses.Add(1)
+	return c.load(ctx, key)
+}
+
+// GetAll returns the values of keys, loading the missing ones with the bulk loader if one is set and with the loader
+// otherwise. Keys already being loaded, by Get or another GetAll, wait for that load instead of being loaded again,
+// and loads are shared and detached from ctx as with Get.
+//
+// Returns:
+//   - The values that were cached or loaded; keys that failed to load are absent
+//   - The first error of the loads the keys waited for with a bulk loader, or the joined errors of the individual
+//     loads otherwise; ctx.Err() if ctx is done first
+func (c *LoadingCache[K, V]) GetAll(ctx context.Context, keys []K) (map[K]V, error) {
+	result := make(map[K]V, len(keys))
+	var missing []K
+	for _, key := range keys {
+		if _, seen := result[key]; seen {
+			continue
+		}
+		if v, ok := c.lookup(key); ok {
+			c.hits.Add(1)
+			result[key] = v
+		} else {
+			c.misses.Add(1)
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	if c.bulkLoader != nil {
+		loadCtx := context.WithoutCancel(ctx)
+		calls := c.flight.DoAll(missing, func(keys []K) (map[K]V, error) {
+			loaded, err := callFetcher(loadCtx, c.timeout, func(ctx context.Context) (map[K]V, error) {
+				return c.bulkLoader(ctx, keys)
+			})
+			if err != nil {
+				c.loadErrors.Add(1)
+				return nil, err
+			}
+			c.loadSuccesses.Add(1)
+			for _, key := range keys {
+				if v, ok := loaded[key]; ok {
+					c.store(key, v, c.loadedTTL(key, v))
+				}
+			}
+			return loaded, nil
+		})
+
+		var firstErr error
+		for _, key := range missing {
+			v, err := calls[key].wait(ctx)
+			switch {
+			case err == nil:
+				result[key] = v
+			case ctx.Err() != nil:
+				return result, ctx.Err()
+			case errors.Is(err, errNotLoaded): // not found
+			case firstErr == nil:
+				firstErr = err
+			}
+		}
+		return result, firstErr
+	}
+
+	var errs []error
+	for _, key := range missing {
+		v, err := c.load(ctx, key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("key %v: %w", key, err))
+			continue
+		}
+		result[key] = v
+	}
+	return result, errors.Join(errs...)
+}
+
+// Set stores value for key with the cache's TTL, replacing any cached value.
+func (c *LoadingCache[K, V]) Set(key K, value V) {
+	c.store(key, value, c.ttl)
+}
+
+// SetWithTTL stores value for key with its own TTL, replacing any cached value.
+//
+// Panics:
+//   - If ttl is not positive
+func (c *LoadingCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	if ttl <= 0 {
+		panic("ttl must be greater than zero")
+	}
+	c.store(key, value, ttl)
+}
+
+// Invalidate removes key from the cache.
+func (c *LoadingCache[K, V]) Invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+}
+
+// InvalidateAll removes every entry from the cache.
+func (c *LoadingCache[K, V]) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[K]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns the number of cached entries, including expired entries that have not been removed yet.
+func (c *LoadingCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *LoadingCache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		LoadSuccesses: c.loadSuccesses.Load(),
+		LoadErrors:    c.loadErrors.Load(),
+		Evictions:     c.evictions.Load(),
+	}
+}
+
+// lookup returns the unexpired value of key and marks it as recently used. Expired entries are removed.
+func (c *LoadingCache[K, V]) lookup(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	entry := e.Value.(*cacheEntry[K, V])
+	if !c.clock.Now().Before(entry.expires) {
+		c.remove(e)
+		var zero V
+		return zero, false
+	}
+	c.lru.MoveToFront(e)
+	return entry.value, true
+}
+
+// load calls the loader for key through the singleflight group and stores a successful result. The loader runs
+// detached from the cancellation of ctx, see Get. If the key was being loaded by a bulk load that does not return it,
+// the loader is called after all.
+func (c *LoadingCache[K, V]) load(ctx context.Context, key K) (V, error) {
+	loadCtx := context.WithoutCancel(ctx)
+	for {
+		calls := c.flight.DoAll([]K{key}, func([]K) (map[K]V, error) {
+			v, err := callFetcher(loadCtx, c.timeout, func(ctx context.Context) (V, error) { return c.loader(ctx, key) })
+			if err != nil {
+				c.loadErrors.Add(1)
+				return nil, err
+			}
+			c.loadSuccesses.Add(1)
+			c.store(key, v, c.loadedTTL(key, v))
+			return map[K]V{key: v}, nil
+		})
+		if v, err := calls[key].wait(ctx); !errors.Is(err, errNotLoaded) {
+			return v, err
+		}
+	}
+}
+
+// loadedTTL returns the TTL of a loaded value: the result of the TTL function if one is set and positive, and the
+// cache's TTL otherwise.
+func (c *LoadingCache[K, V]) loadedTTL(key K, value V) time.Duration {
+	if c.ttlFunc != nil {
+		if ttl := c.ttlFunc(key, value); ttl > 0 {
+			return ttl
+		}
+	}
+	return c.ttl
+}
+
+// store inserts or replaces the entry of key and evicts the least recently used entries beyond maxSize.
+func (c *LoadingCache[K, V]) store(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.clock.Now().Add(ttl)
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*cacheEntry[K, V])
+		entry.value, entry.expires = value, expires
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry[K, V]{key: key, value: value, expires: expires})
+	for c.lru.Len() > c.maxSize {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// remove deletes an element from the list and the index. The caller must hold the lock.
+func (c *LoadingCache[K, V]) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry[K, V]).key)
+}
@@ -0,0 +1,312 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingCacheTTLAndStats(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	var loads atomic.Int32
+	c := NewLoadingCache(func(_ context.Context, k string) (int, error) {
+		loads.Add(1)
+		return len(k), nil
+	}, 30*time.Millisecond, 10).WithClock(clock)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if v, err := c.Get(ctx, "tenant"); err != nil || v != 6 {
+			t.Fatalf("expected 6, got %d, %v", v, err)
+		}
+	}
+	if loads.Load() != 1 {
+		t.Fatalf("expected one load within the TTL, got %d", loads.Load())
+	}
+
+	clock.Advance(30 * time.Millisecond)
+	_, _ = c.Get(ctx, "tenant")
+	if loads.Load() != 2 {
+		t.Fatalf("expected a reload after the TTL, got %d loads", loads.Load())
+	}
+
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 2 || s.LoadSuccesses != 2 || s.LoadErrors != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s.HitRate() != 0.5 {
+		t.Fatalf("expected hit rate 0.5, got %v", s.HitRate())
+	}
+}
+
+func TestLoadingCacheLRUEviction(t *testing.T) {
+	c := NewLoadingCache(func(_ context.Context, k int) (int, error) { return k * 10, nil }, time.Minute, 2)
+	ctx := context.Background()
+
+	_, _ = c.Get(ctx, 1)
+	_, _ = c.Get(ctx, 2)
+	_, _ = c.Get(ctx, 1) // 2 is now the least recently used
+	_, _ = c.Get(ctx, 3)
+
+	if c.Len() != 2 || c.Stats().Evictions != 1 {
+		t.Fatalf("expected 2 entries and 1 eviction, got %d and %+v", c.Len(), c.Stats())
+	}
+	if _, ok := c.lookup(2); ok {
+		t.Fatal("expected 2 to be evicted")
+	}
+	if _, ok := c.lookup(1); !ok {
+		t.Fatal("expected 1 to stay cached")
+	}
+}
+
+func TestLoadingCacheSingleflightAndErrors(t *testing.T) {
+	var loads atomic.Int32
+	fail := errors.New("tenant unavailable")
+	release := make(chan struct{})
+	c := NewLoadingCache(func(_ context.Context, k string) (string, error) {
+		loads.Add(1)
+		<-release
+		if k == "bad" {
+			return "", fail
+		}
+		return "cfg-" + k, nil
+	}, time.Minute, 10)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.Get(ctx, "a"); err != nil || v != "cfg-a" {
+				t.Errorf("expected cfg-a, got %q, %v", v, err)
+			}
+		}()
+	}
+	waitFor(t, func() bool { // the other nine lookups have joined the first one's load
+		c.flight.mu.Lock()
+		defer c.flight.mu.Unlock()
+		call, ok := c.flight.calls["a"]
+		return ok && call.dups == 9
+	})
+	close(release)
+	wg.Wait()
+	if loads.Load() != 1 {
+		t.Fatalf("expected concurrent lookups to share one load, got %d", loads.Load())
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(ctx, "bad"); !errors.Is(err, fail) {
+			t.Fatalf("expected the loader error, got %v", err)
+		}
+	}
+	if loads.Load() != 3 || c.Stats().LoadErrors != 2 {
+		t.Fatalf("expected errors not to be cached, got %d loads and %+v", loads.Load(), c.Stats())
+	}
+}
+
+func TestLoadingCacheSharedLoadOutlivesCanceledCaller(t *testing.T) {
+	release := make(chan struct{})
+	deadlines := make(chan bool, 1)
+	c := NewLoadingCache(func(ctx context.Context, k string) (string, error) {
+		_, ok := ctx.Deadline()
+		deadlines <- ok
+		select {
+		case <-release:
+			return "cfg-" + k, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}, time.Minute, 10).WithLoadTimeout(time.Hour)
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := c.Get(first, "a")
+		firstErr <- err
+	}()
+	if !<-deadlines {
+		t.Fatal("expected the load timeout to apply to the loader's context")
+	}
+
+	second := make(chan string)
+	go func() {
+		v, err := c.Get(context.Background(), "a")
+		if err != nil {
+			t.Errorf("expected the second caller to be unaffected by the first one's cancellation, got %v", err)
+		}
+		second <- v
+	}()
+	waitFor(t, func() bool { // the second lookup has joined the first one's load
+		c.flight.mu.Lock()
+		defer c.flight.mu.Unlock()
+		call, ok := c.flight.calls["a"]
+		return ok && call.dups == 1
+	})
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the canceled caller to stop waiting, got %v", err)
+	}
+	close(release)
+	if v := <-second; v != "cfg-a" {
+		t.Fatalf("expected the shared load to complete, got %q", v)
+	}
+}
+
+func TestLoadingCacheGetAll(t *testing.T) {
+	c := NewLoadingCache(func(_ context.Context, k int) (int, error) {
+		if k < 0 {
+			return 0, errors.New("negative")
+		}
+		return k * 2, nil
+	}, time.Minute, 10)
+	ctx := context.Background()
+	c.Set(1, 100)
+
+	got, err := c.GetAll(ctx, []int{1, 2, -1})
+	if err == nil || got[1] != 100 || got[2] != 4 || len(got) != 2 {
+		t.Fatalf("expected partial result and an error, got %v, %v", got, err)
+	}
+
+	var bulkKeys []int
+	c.WithBulkLoader(func(_ context.Context, keys []int) (map[int]int, error) {
+		bulkKeys = keys
+		m := make(map[int]int)
+		for _, k := range keys {
+			if k != 5 {
+				m[k] = k * 3
+			}
+		}
+		return m, nil
+	})
+	got, err = c.GetAll(ctx, []int{2, 3, 4, 5})
+	if err != nil || len(bulkKeys) != 3 || got[2] != 4 || got[3] != 9 || got[4] != 12 || len(got) != 3 {
+		t.Fatalf("expected one bulk load of the missing keys, got %v, %v (loaded %v)", got, err, bulkKeys)
+	}
+	if v, err := c.Get(ctx, 3); err != nil || v != 9 {
+		t.Fatalf("expected bulk-loaded values to be cached, got %d, %v", v, err)
+	}
+}
+
+func TestLoadingCacheGetAllSharesLoadsWithGet(t *testing.T) {
+	var loads, bulkLoads atomic.Int32
+	release := make(chan struct{})
+	bulkKeys := make(chan []int, 1)
+	c := NewLoadingCache(func(_ context.Context, k int) (int, error) {
+		loads.Add(1)
+		<-release
+		return k * 2, nil
+	}, time.Minute, 10)
+	c.WithBulkLoader(func(_ context.Context, keys []int) (map[int]int, error) {
+		bulkLoads.Add(1)
+		bulkKeys <- keys
+		<-release
+		m := make(map[int]int)
+		for _, k := range keys {
+			if k != 4 {
+				m[k] = k * 3
+			}
+		}
+		return m, nil
+	})
+	ctx := context.Background()
+	joined := func(key, dups int) func() bool {
+		return func() bool {
+			c.flight.mu.Lock()
+			defer c.flight.mu.Unlock()
+			call, ok := c.flight.calls[key]
+			return ok && call.dups == dups
+		}
+	}
+
+	got := make(chan int, 3)
+	go func() { v, _ := c.Get(ctx, 1); got <- v }()
+	waitFor(t, joined(1, 0)) // the Get of 1 is loading
+
+	all := make(chan map[int]int)
+	go func() { m, _ := c.GetAll(ctx, []int{1, 2, 4}); all <- m }()
+	if keys := <-bulkKeys; len(keys) != 2 || keys[0] != 2 || keys[1] != 4 {
+		t.Fatalf("expected the bulk loader to skip the key already being loaded, got %v", keys)
+	}
+	waitFor(t, joined(1, 1))
+
+	go func() { v, _ := c.Get(ctx, 2); got <- v }() // joins the bulk load
+	go func() { v, _ := c.Get(ctx, 4); got <- v }() // joins the bulk load, which does not return 4
+	waitFor(t, joined(2, 1))
+	waitFor(t, joined(4, 1))
+	close(release)
+
+	m := <-all
+	if len(m) != 2 || m[1] != 2 || m[2] != 6 {
+		t.Fatalf("expected 1 from the Get's load and 2 from the bulk load, got %v", m)
+	}
+	sum := <-got + <-got + <-got
+	if sum != 2+6+8 {
+		t.Fatalf("expected 2, 6 and the individually loaded 8, got a sum of %d", sum)
+	}
+	if loads.Load() != 2 || bulkLoads.Load() != 1 {
+		t.Fatalf("expected each key to be loaded once, got %d loads and %d bulk loads", loads.Load(), bulkLoads.Load())
+	}
+}
+
+func TestLoadingCacheSetWithTTL(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	c := NewLoadingCache(func(_ context.Context, k string) (string, error) { return "loaded", nil }, time.Minute, 10).WithClock(clock)
+	c.SetWithTTL("k", "short", 10*time.Millisecond)
+	clock.Advance(9 * time.Millisecond)
+	if v, _ := c.Get(context.Background(), "k"); v != "short" {
+		t.Fatalf("expected the stored value, got %q", v)
+	}
+	clock.Advance(time.Millisecond)
+	if v, _ := c.Get(context.Background(), "k"); v != "loaded" {
+		t.Fatalf("expected the entry TTL to expire, got %q", v)
+	}
+}
+
+func TestLoadingCacheTTLFunc(t *testing.T) {
+	type token struct {
+		id      int
+		expires time.Duration
+	}
+	clock := NewFakeClock(fakeEpoch)
+	var loads atomic.Int32
+	c := NewLoadingCache(func(_ context.Context, k string) (token, error) {
+		ttl := time.Minute
+		if k == "short" {
+			ttl = time.Second
+		}
+		return token{id: int(loads.Add(1)), expires: ttl}, nil
+	}, time.Hour, 10).WithClock(clock).WithTTLFunc(func(_ string, v token) time.Duration { return v.expires })
+	c.WithBulkLoader(func(_ context.Context, keys []string) (map[string]token, error) {
+		m := make(map[string]token)
+		for _, k := range keys {
+			m[k] = token{id: int(loads.Add(1)), expires: 0} // falls back to the cache's TTL
+		}
+		return m, nil
+	})
+
+	ctx := context.Background()
+	short, _ := c.Get(ctx, "short")
+	long, _ := c.Get(ctx, "long")
+	bulk, _ := c.GetAll(ctx, []string{"bulk"})
+
+	clock.Advance(2 * time.Second)
+	if v, _ := c.Get(ctx, "short"); v.id == short.id {
+		t.Fatal("expected the short-lived entry to expire after its own TTL")
+	}
+	if v, _ := c.Get(ctx, "long"); v.id != long.id {
+		t.Fatal("expected the long-lived entry to be cached")
+	}
+
+	clock.Advance(time.Minute)
+	if v, _ := c.Get(ctx, "long"); v.id == long.id {
+		t.Fatal("expected the long-lived entry to expire after its own TTL")
+	}
+	if v, _ := c.GetAll(ctx, []string{"bulk"}); v["bulk"].id != bulk["bulk"].id {
+		t.Fatal("expected a non-positive TTL to fall back to the cache's TTL")
+	}
+}
@@ -1,7 +1,11 @@
 // Package util provides utility functions and types for common operations.
 package util
 
-import "math"
+import (
+	"math"
+
+	"golang.org/x/exp/constraints"
+)
 
 // AlmostEqual compares two floating-point numbers and determines if they are approximately equal.
 // It uses a fixed epsilon value of 1e-6 for the comparison, which is suitable for most general-purpose
@@ -17,3 +21,52 @@ func AlmostEqual[T ~float64 | ~float32](a, b T) bool {
 	delta := math.Abs(float64(a - b))
 	return delta < 1e-6
 }
+
+// AbsoluteTolerance returns an equality function that treats two floating-point numbers as equal when their absolute
+// difference is at most eps. It is suitable for PubSubDebouncer.WithEqual.
+//
+// Parameters:
+//   - eps: The largest difference still considered equal
+//
+// Returns:
+//   - A function reporting whether a and b are within eps of each other
+func AbsoluteTolerance[T ~float64 | ~float32](eps float64) func(a, b T) bool {
+	return func(a, b T) bool {
+		return math.Abs(float64(a)-float64(b)) <= eps
+	}
+}
+
+// RelativeTolerance returns an equality function that treats two floating-point numbers as equal when their
+// difference is at most rel times the larger magnitude of the two. It is suitable for PubSubDebouncer.WithEqual.
+//
+// Parameters:
+//   - rel: The relative tolerance, e.g. 1e-9
+//
+// Returns:
+//   - A function reporting whether a and b are within rel of each other, relatively
+func RelativeTolerance[T ~float64 | ~float32](rel float64) func(a, b T) bool {
+	return func(a, b T) bool {
+		fa, fb := float64(a), float64(b)
+		return math.Abs(fa-fb) <= rel*math.Max(math.Abs(fa), math.Abs(fb))
+	}
+}
+
+// PercentChange returns an equality function that treats a new value b as equal to a previous value a unless it
+// differs from a by more than percent percent of a. Used with PubSubDebouncer.WithEqual it implements a change
+// threshold: listeners are only notified when the value moved by more than percent since the last broadcast.
+// Any change away from a previous value of zero counts as exceeding the threshold.
+//
+// Parameters:
+//   - percent: The threshold in percent, e.g. 0.5 for half a percent
+//
+// Returns:
+//   - A function reporting whether the change from a to b is within the threshold
+func PercentChange[T constraints.Integer | constraints.Float](percent float64) func(a, b T) bool {
+	return func(a, b T) bool {
+		fa, fb := float64(a), float64(b)
+		if fa == 0 {
+			return fb == 0
+		}
+		return math.Abs(fb-fa)/math.Abs(fa)*100 <= percent
+	}
+}
@@ -0,0 +1,39 @@
+package util
+
+import "testing"
+
+func TestAbsoluteTolerance(t *testing.T) {
+	eq := AbsoluteTolerance[float64](0.01)
+	if !eq(1.0, 1.005) {
+		t.Error("expected values within 0.01 to be equal")
+	}
+	if eq(1.0, 1.02) {
+		t.Error("expected values 0.02 apart to differ")
+	}
+}
+
+func TestRelativeTolerance(t *testing.T) {
+	eq := RelativeTolerance[float32](1e-3)
+	if !eq(1000, 1000.5) {
+		t.Error("expected values within 0.1% to be equal")
+	}
+	if eq(1, 1.01) {
+		t.Error("expected values 1% apart to differ")
+	}
+	if !eq(0, 0) {
+		t.Error("expected zeros to be equal")
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	eq := PercentChange[int](5)
+	if !eq(100, 105) || !eq(100, 95) {
+		t.Error("expected a 5% change to be within the threshold")
+	}
+	if eq(100, 106) {
+		t.Error("expected a 6% change to exceed the threshold")
+	}
+	if eq(0, 1) || !eq(0, 0) {
+		t.Error("expected any change away from zero to exceed the threshold")
+	}
+}
@@ -0,0 +1,201 @@
+// Package util provides utility functions and types for common operations.
+package util
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Pipeline chains WorkerPools into a sequence of typed stages. Items posted to the pipeline flow from one stage to the
+// next, each stage running with its own number of workers and buffer size. Because every hand-off is a blocking Post
+// on a bounded WorkerPool, a slow stage applies backpressure all the way back to the caller of Post.
+//
+// The first error returned by any stage cancels the whole pipeline: the context handed to stage functions is canceled,
+// items still in flight are dropped, and Err reports the failure. Close cascades through the stages so that Next
+// reports false once every stage has drained.
+//
+// A Pipeline is built with NewPipeline and extended with AddStage. I is the input type of the first stage and O is the
+// output type of the last stage.
+type Pipeline[I any, O any] struct {
+	run    *pipelineRun
+	post   func(I)
+	close  func()
+	next   func() (stageResult[O], bool)
+	stages int
+}
+
+// pipelineRun holds the state shared by every stage of a pipeline.
+type pipelineRun struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	err    error
+}
+
+// stageResult carries a stage output; ok is false when the item was dropped because the pipeline failed.
+type stageResult[T any] struct {
+	v  T
+	ok bool
+}
+
+// NewPipeline creates a Pipeline whose first stage applies f to every posted item.
+//
+// Parameters:
+//   - ctx: The parent context; canceling it cancels the pipeline
+//   - f: The stage function, called with the pipeline context
+//   - numWorkers: The number of workers running f concurrently
+//   - buffer: The number of items that may queue in front of and behind the stage
+//
+// Returns:
+//   - A pointer to a new Pipeline instance
+//
+// Panics:
+//   - If buffer is negative or numWorkers is less than 1
+func NewPipeline[I any, O any](ctx context.Context, f func(context.Context, I) (O, error), numWorkers int, buffer int) *Pipeline[I, O] {
+	run := &pipelineRun{parent: ctx}
+	run.ctx, run.cancel = context.WithCancel(ctx)
+
+	pool := NewWorkerPool(pipelineStage(run, 0, f), buffer, numWorkers)
+
+	return &Pipeline[I, O]{
+		run:    run,
+		post:   pool.Post,
+		close:  pool.Close,
+		next:   pool.Next,
+		stages: 1,
+	}
+}
+
+// AddStage appends a stage applying f to the output of p and returns the extended pipeline. The returned pipeline
+// replaces p; p must not be used afterward.
+//
+// Parameters:
+//   - p: The pipeline to extend
+//   - f: The stage function, called with the pipeline context
+//   - numWorkers: The number of workers running f concurrently
+//   - buffer: The number of items that may queue in front of and behind the stage
+//
+// Returns:
+//   - A pointer to the extended Pipeline
+//
+// Panics:
+//   - If buffer is negative or numWorkers is less than 1
+func AddStage[I any, M any, O any](p *Pipeline[I, M], f func(context.Context, M) (O, error), numWorkers int, buffer int) *Pipeline[I, O] {
+	pool := NewWorkerPool(pipelineStage(p.run, p.stages, f), buffer, numWorkers)
+
+	// forward the previous stage into this one; Post blocks while this stage is full, which propagates backpressure
+	go func() {
+		for {
+			r, ok := p.next()
+			if !ok {
+				break
+			}
+			if r.ok {
+				pool.Post(r.v)
+			}
+		}
+		pool.Close()
+	}()
+
+	return &Pipeline[I, O]{
+		run:    p.run,
+		post:   p.post,
+		close:  p.close,
+		next:   pool.Next,
+		stages: p.stages + 1,
+	}
+}
+
+// pipelineStage wraps a stage function so that it skips work once the pipeline has failed and cancels the pipeline on error.
+func pipelineStage[A any, B any](run *pipelineRun, index int, f func(context.Context, A) (B, error)) func(A) stageResult[B] {
+	return func(a A) stageResult[B] {
+		if run.ctx.Err() != nil {
+			return stageResult[B]{}
+		}
+		b, err := f(run.ctx, a)
+		if err != nil {
+			run.fail(fmt.Errorf("pipeline stage %d: %w", index, err))
+			return stageResult[B]{}
+		}
+		return stageResult[B]{v: b, ok: true}
+	}
+}
+
+// fail records the first error and cancels the pipeline context.
+func (r *pipelineRun) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// Post submits an item to the first stage. It blocks while the first stage is full.
+//
+// Returns:
+//   - nil if the item was accepted, or the pipeline error if the pipeline has already failed or been canceled
+func (p *Pipeline[I, O]) Post(v I) error {
+	if p.run.ctx.Err() != nil {
+		return p.Err()
+	}
+	p.post(v)
+	return nil
+}
+
+// Close signals that no more items will be posted. Each stage is closed once the stage before it has drained.
+func (p *Pipeline[I, O]) Close() {
+	p.close()
+}
+
+// Cancel aborts the pipeline. Items still in flight are dropped and Err reports context.Canceled.
+// Close must still be called to let the stages drain.
+func (p *Pipeline[I, O]) Cancel() {
+	p.run.fail(context.Canceled)
+}
+
+// Next returns the next output of the last stage. Items dropped because the pipeline failed are skipped.
+//
+// Returns:
+//   - The next output value
+//   - false once the pipeline has been closed and every stage has drained
+func (p *Pipeline[I, O]) Next() (O, bool) {
+	for {
+		r, ok := p.next()
+		if !ok {
+			p.run.cancel() // release the context once everything has drained
+			var zero O
+			return zero, false
+		}
+		if r.ok {
+			return r.v, true
+		}
+	}
+}
+
+// Wait discards any remaining outputs until every stage has drained and then returns the pipeline error.
+// Call Close before Wait, otherwise Wait blocks forever.
+//
+// Returns:
+//   - The first stage error, context.Canceled after Cancel, the parent context error, or nil
+func (p *Pipeline[I, O]) Wait() error {
+	for {
+		if _, ok := p.Next(); !ok {
+			break
+		}
+	}
+	return p.Err()
+}
+
+// Err returns the first stage error, context.Canceled after Cancel, or the parent context error. It returns nil while
+// the pipeline is healthy.
+func (p *Pipeline[I, O]) Err() error {
+	p.run.mu.Lock()
+	defer p.run.mu.Unlock()
+	if p.run.err != nil {
+		return p.run.err
+	}
+	return p.run.parent.Err()
+}
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	p1 := NewPipeline(context.Background(), func(_ context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	}, 2, 1)
+	p2 := AddStage(p1, func(_ context.Context, v int) (int, error) { return v * v, nil }, 3, 0)
+	p := AddStage(p2, func(_ context.Context, v int) (string, error) { return strconv.Itoa(v), nil }, 1, 2)
+
+	go func() {
+		for i := 1; i <= 10; i++ {
+			if err := p.Post(strconv.Itoa(i)); err != nil {
+				t.Errorf("unexpected post error: %v", err)
+			}
+		}
+		p.Close()
+	}()
+
+	sum := 0
+	count := 0
+	for {
+		s, ok := p.Next()
+		if !ok {
+			break
+		}
+		v, _ := strconv.Atoi(s)
+		sum += v
+		count++
+	}
+
+	if count != 10 || sum != 385 {
+		t.Fatalf("expected 10 results summing to 385, got %d summing to %d", count, sum)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPipelineErrorCancels(t *testing.T) {
+	boom := errors.New("boom")
+	var lastStage atomic.Int32
+
+	p1 := NewPipeline(context.Background(), func(ctx context.Context, v int) (int, error) {
+		if v == 3 {
+			return 0, boom
+		}
+		return v, nil
+	}, 1, 0)
+	p := AddStage(p1, func(ctx context.Context, v int) (int, error) {
+		lastStage.Add(1)
+		return v, nil
+	}, 1, 0)
+
+	go func() {
+		for i := 1; i <= 100; i++ {
+			if p.Post(i) != nil {
+				break
+			}
+		}
+		p.Close()
+	}()
+
+	err := p.Wait()
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if n := lastStage.Load(); n > 3 {
+		t.Fatalf("expected at most 3 items past the first stage, got %d", n)
+	}
+	if p.Post(1) == nil {
+		t.Fatal("expected post to fail after the pipeline failed")
+	}
+}
+
+func TestPipelineParentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPipeline(ctx, func(ctx context.Context, v int) (int, error) { return v, nil }, 1, 0)
+
+	cancel()
+	if err := p.Post(1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	p.Close()
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -23,6 +23,17 @@ func (s *PubSub[T]) Register(buffSize uint) <-chan T {
 	return c
 }
 
+// registerPrimed registers a new reception channel of size buffSize that already holds initial. buffSize must be at
+// least 1.
+func (s *PubSub[T]) registerPrimed(buffSize uint, initial T) <-chan T {
+	c := make(chan T, buffSize)
+	c <- initial
+	s.Lock()
+	s.clients = append(s.clients, c)
+	s.Unlock()
+	return c
+}
+
 // Broadcast sends a message to all registered channels in the PubSub.
 // It acquires a read-lock to prevent changes to the clients slice during the snapshot.
 // The clients slice is copied into a new buffer for non-concurrent iteration, then the lock is released.
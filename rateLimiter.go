@@ -0,0 +1,281 @@
+// Package util provides utility functions and types for common operations.
+package util
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RateLimiter controls how frequently events may happen.
+//
+// Allow reports whether an event may happen now and consumes capacity if so. Reserve always consumes capacity and
+// reports how long the caller must wait before acting. Wait blocks until an event may happen or ctx is done.
+type RateLimiter interface {
+	Allow() bool
+	Reserve() *Reservation
+	Wait(ctx context.Context) error
+}
+
+// Reservation holds capacity reserved from a RateLimiter for an event at a future time.
+type Reservation struct {
+	clock  Clock
+	at     time.Time
+	cancel func()
+	once   sync.Once
+}
+
+// Delay returns how long the caller must wait before acting on the reservation. It is zero if the event may
+// happen now.
+func (r *Reservation) Delay() time.Duration {
+	return max(r.at.Sub(r.clock.Now()), 0)
+}
+
+// Time returns the time at which the reserved event may happen.
+func (r *Reservation) Time() time.Time {
+	return r.at
+}
+
+// Cancel returns the reserved capacity to the limiter so that other events may use it. It is safe to call more than
+// once; only the first call has an effect. Canceling a reservation whose time has already passed does nothing.
+func (r *Reservation) Cancel() {
+	r.once.Do(func() {
+		if r.cancel != nil && r.clock.Now().Before(r.at) {
+			r.cancel()
+		}
+	})
+}
+
+// waitReservation blocks until r is due or ctx is done, in which case the reservation is canceled.
+func waitReservation(ctx context.Context, r *Reservation) error {
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(r.at) {
+		r.Cancel()
+		return fmt.Errorf("rate limiter wait of %v would exceed context deadline", delay)
+	}
+
+	timer := r.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// TokenBucket is a RateLimiter that refills tokens at a constant rate up to a maximum burst size. Each event
+// consumes one token. TokenBucket is safe for concurrent use.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64 // may be negative while reservations are outstanding
+	last   time.Time
+	clock  Clock
+}
+
+// NewTokenBucket creates a TokenBucket that starts full.
+//
+// Parameters:
+//   - rate: The number of tokens added per second
+//   - burst: The maximum number of tokens the bucket holds
+//
+// Returns:
+//   - A pointer to a new TokenBucket instance
+//
+// Panics:
+//   - If rate is not positive or burst is less than 1
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 {
+		panic("rate must be greater than zero")
+	}
+	if burst < 1 {
+		panic("burst must be greater than zero")
+	}
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now(), clock: SystemClock}
+}
+
+// WithClock sets the Clock used for refills and waiting and returns the TokenBucket for chaining. The bucket starts
+// full at the clock's current time. It should be called before the TokenBucket is used.
+func (tb *TokenBucket) WithClock(clock Clock) *TokenBucket {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.clock = clock
+	tb.tokens = tb.burst
+	tb.last = clock.Now()
+	return tb
+}
+
+// refill adds the tokens accrued since the last call. The caller must hold the lock.
+func (tb *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(tb.last); elapsed > 0 {
+		tb.tokens = min(tb.burst, tb.tokens+elapsed.Seconds()*tb.rate)
+		tb.last = now
+	}
+}
+
+// Allow reports whether a token is available now, consuming it if so.
+func (tb *TokenBucket) Allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill(tb.clock.Now())
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true
+	}
+	return false
+}
+
+// Reserve consumes a token, borrowing against future refills if the bucket is empty.
+//
+// Returns:
+//   - A Reservation whose Delay is the time until the borrowed token has been refilled
+func (tb *TokenBucket) Reserve() *Reservation {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.clock.Now()
+	tb.refill(now)
+	tb.tokens--
+
+	r := &Reservation{clock: tb.clock, at: now}
+	if tb.tokens < 0 {
+		r.at = now.Add(time.Duration(-tb.tokens / tb.rate * float64(time.Second)))
+		r.cancel = func() {
+			tb.mu.Lock()
+			tb.refill(tb.clock.Now())
+			tb.tokens = min(tb.burst, tb.tokens+1)
+			tb.mu.Unlock()
+		}
+	}
+	return r
+}
+
+// Wait blocks until a token is available or ctx is done.
+//
+// Returns:
+//   - nil once a token has been acquired, or an error if ctx ends first or its deadline is too soon
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	return waitReservation(ctx, tb.Reserve())
+}
+
+// SlidingWindow is a RateLimiter that allows at most limit events in any window of the given duration. It keeps a
+// log of event times, so its memory use grows with limit. SlidingWindow is safe for concurrent use.
+type SlidingWindow struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	log    []time.Time // admitted and reserved event times, ascending
+	clock  Clock
+}
+
+// NewSlidingWindow creates a SlidingWindow limiter.
+//
+// Parameters:
+//   - limit: The maximum number of events in any window
+//   - window: The length of the window
+//
+// Returns:
+//   - A pointer to a new SlidingWindow instance
+//
+// Panics:
+//   - If limit is less than 1 or window is not positive
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	if limit < 1 {
+		panic("limit must be greater than zero")
+	}
+	if window <= 0 {
+		panic("window must be greater than zero")
+	}
+	return &SlidingWindow{limit: limit, window: window, log: make([]time.Time, 0, limit), clock: SystemClock}
+}
+
+// WithClock sets the Clock used for the window and waiting and returns the SlidingWindow for chaining. It should be
+// called before the SlidingWindow is used.
+func (sw *SlidingWindow) WithClock(clock Clock) *SlidingWindow {
+	sw.mu.Lock()
+	sw.clock = clock
+	sw.mu.Unlock()
+	return sw
+}
+
+// next prunes expired entries and returns the earliest time a new event may happen. The caller must hold the lock.
+func (sw *SlidingWindow) next(now time.Time) time.Time {
+	cutoff := now.Add(-sw.window)
+	i := 0
+	for i < len(sw.log) && !sw.log[i].After(cutoff) {
+		i++
+	}
+	sw.log = sw.log[i:]
+
+	at := now
+	if n := len(sw.log); n > 0 {
+		at = maxTime(at, sw.log[n-1]) // keep the log ordered so that only the trailing window needs checking
+		if n >= sw.limit {
+			at = maxTime(at, sw.log[n-sw.limit].Add(sw.window))
+		}
+	}
+	return at
+}
+
+// Allow reports whether an event may happen now, recording it if so.
+func (sw *SlidingWindow) Allow() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := sw.clock.Now()
+	if at := sw.next(now); at.After(now) {
+		return false
+	}
+	sw.log = append(sw.log, now)
+	return true
+}
+
+// Reserve records an event at the earliest time the window permits.
+//
+// Returns:
+//   - A Reservation whose Delay is the time until the event may happen
+func (sw *SlidingWindow) Reserve() *Reservation {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := sw.clock.Now()
+	at := sw.next(now)
+	sw.log = append(sw.log, at)
+
+	r := &Reservation{clock: sw.clock, at: at}
+	if at.After(now) {
+		r.cancel = func() {
+			sw.mu.Lock()
+			defer sw.mu.Unlock()
+			if i := slices.IndexFunc(sw.log, at.Equal); i >= 0 {
+				sw.log = slices.Delete(sw.log, i, i+1)
+			}
+		}
+	}
+	return r
+}
+
+// Wait blocks until an event may happen or ctx is done.
+//
+// Returns:
+//   - nil once the event has been admitted, or an error if ctx ends first or its deadline is too soon
+func (sw *SlidingWindow) Wait(ctx context.Context) error {
+	return waitReservation(ctx, sw.Reserve())
+}
+
+// maxTime returns the later of a and b.
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
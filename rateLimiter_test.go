@@ -0,0 +1,138 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllow(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	tb := NewTokenBucket(10, 3).WithClock(c)
+
+	for i := 0; i < 3; i++ {
+		if !tb.Allow() {
+			t.Fatalf("expected burst token %d to be allowed", i)
+		}
+	}
+	if tb.Allow() {
+		t.Fatal("expected empty bucket to deny")
+	}
+
+	c.Advance(50 * time.Millisecond)
+	if tb.Allow() {
+		t.Fatal("expected half a token to be denied")
+	}
+	c.Advance(50 * time.Millisecond)
+	if !tb.Allow() {
+		t.Fatal("expected a refilled token to be allowed")
+	}
+}
+
+func TestTokenBucketReserveAndCancel(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	tb := NewTokenBucket(10, 1).WithClock(c)
+
+	if d := tb.Reserve().Delay(); d != 0 {
+		t.Fatalf("expected no delay for the burst token, got %v", d)
+	}
+	r := tb.Reserve()
+	if d := r.Delay(); d != 100*time.Millisecond {
+		t.Fatalf("expected 100ms delay, got %v", d)
+	}
+	r.Cancel()
+	if d := tb.Reserve().Delay(); d != 100*time.Millisecond {
+		t.Fatalf("expected the canceled token to be returned, got delay %v", d)
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	tb := NewTokenBucket(50, 1).WithClock(c)
+
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 4; i++ {
+			if err := tb.Wait(context.Background()); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for i := 0; i < 3; i++ {
+		c.BlockUntil(1) // the next wait for a token, 20ms away
+		c.Advance(20 * time.Millisecond)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), c.Now().Add(5*time.Millisecond))
+	defer cancel()
+	if err := tb.Wait(ctx); err == nil {
+		t.Fatal("expected wait beyond the context deadline to fail")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	sw := NewSlidingWindow(3, 50*time.Millisecond).WithClock(c)
+
+	for i := 0; i < 3; i++ {
+		if !sw.Allow() {
+			t.Fatalf("expected event %d to be allowed", i)
+		}
+	}
+	if sw.Allow() {
+		t.Fatal("expected fourth event in the window to be denied")
+	}
+
+	r := sw.Reserve()
+	if d := r.Delay(); d != 50*time.Millisecond {
+		t.Fatalf("expected a delay until the window has passed, got %v", d)
+	}
+	r.Cancel()
+	if len(sw.log) != 3 {
+		t.Fatalf("expected the canceled reservation to be removed, log has %d entries", len(sw.log))
+	}
+
+	c.Advance(49 * time.Millisecond)
+	if sw.Allow() {
+		t.Fatal("expected event to be denied before the window has passed")
+	}
+	c.Advance(time.Millisecond)
+	if !sw.Allow() {
+		t.Fatal("expected event to be allowed once the window has passed")
+	}
+}
+
+func TestWorkerPoolWithRateLimiter(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	wp := NewWorkerPool(func(v int) int { return v }, 10, 4).WithRateLimiter(NewTokenBucket(100, 1).WithClock(c))
+
+	for i := 0; i < 6; i++ {
+		wp.Post(i)
+	}
+	wp.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, ok := wp.Next(); ok; _, ok = wp.Next() {
+		}
+	}()
+
+	// the first item takes the burst token and the other five wait 10ms each at 100/s
+	for i := 0; i < 4; i++ {
+		c.BlockUntil(1)
+		c.Advance(10 * time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("expected workers to be paced to 100/s, finished within 40ms")
+	default:
+	}
+	c.BlockUntil(1)
+	c.Advance(10 * time.Millisecond)
+	<-done
+}
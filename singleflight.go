@@ -0,0 +1,153 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// flightCall is an in-progress or completed call of a singleflight group.
+type flightCall[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+	dups int // callers that joined the call; read once it has completed to report whether its result was shared
+}
+
+// wait blocks until the call has completed or ctx is done, in which case the call continues without the caller.
+func (c *flightCall[V]) wait(ctx context.Context) (V, error) {
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// errNotLoaded completes the call of a key that a DoAll function returned neither a value nor an error for.
+var errNotLoaded = errors.New("singleflight: key not loaded")
+
+// singleflight deduplicates concurrent calls that share a key: while a call for a key is running, other callers for
+// the same key wait for it and receive its result instead of starting their own. The zero value is ready for use.
+type singleflight[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+// Do runs fn for key unless a call for key is already running, in which case it waits for that call. If fn panics,
+// the panic is re-raised in the caller that ran fn, and the waiting callers receive an error describing it.
+//
+// Returns:
+//   - The value and error of the call
+//   - true if the result was given to more than one caller, as with x/sync/singleflight: always for a caller that
+//     waited, and for the caller that ran fn if others waited for it
+func (g *singleflight[K, V]) Do(key K, fn func() (V, error)) (V, error, bool) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.val, c.err, true
+	}
+	if g.calls == nil {
+		g.calls = make(map[K]*flightCall[V])
+	}
+	c := &flightCall[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	returned := false
+	defer func() {
+		if returned {
+			return
+		}
+		r := recover() // nil if fn called runtime.Goexit
+		if r != nil {
+			c.err = fmt.Errorf("singleflight: call panicked: %v", r)
+		} else {
+			c.err = fmt.Errorf("singleflight: call exited without returning")
+		}
+		g.finish(key, c)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	c.val, c.err = fn()
+	returned = true
+	g.finish(key, c) // no caller can join after this, so dups is final
+	return c.val, c.err, c.dups > 0
+}
+
+// finish removes a completed call and releases its waiters.
+func (g *singleflight[K, V]) finish(key K, c *flightCall[V]) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(c.done)
+}
+
+// DoAll is like Do for several keys at once, without blocking: keys with a call running join it, and fn is called
+// once, on a new goroutine, with the remaining keys. The calls started for fn complete with the value fn returns for
+// their key, with the error of fn, or with errNotLoaded if fn returns no value for their key. If fn panics, they fail
+// with an error describing the panic.
+//
+// Returns:
+//   - The call of every key, to wait on
+func (g *singleflight[K, V]) DoAll(keys []K, fn func(keys []K) (map[K]V, error)) map[K]*flightCall[V] {
+	calls := make(map[K]*flightCall[V], len(keys))
+	var started []K
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*flightCall[V])
+	}
+	for _, key := range keys {
+		if _, seen := calls[key]; seen {
+			continue
+		}
+		if c, ok := g.calls[key]; ok {
+			c.dups++
+			calls[key] = c
+			continue
+		}
+		c := &flightCall[V]{done: make(chan struct{})}
+		g.calls[key] = c
+		calls[key] = c
+		started = append(started, key)
+	}
+	g.mu.Unlock()
+	if len(started) == 0 {
+		return calls
+	}
+
+	go func() {
+		var values map[K]V
+		err := errors.New("singleflight: call exited without returning") // replaced unless fn calls runtime.Goexit
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("singleflight: call panicked: %v", r)
+			}
+			g.mu.Lock()
+			for _, key := range started {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			for _, key := range started {
+				c := calls[key]
+				v, ok := values[key]
+				switch {
+				case err != nil:
+					c.err = err
+				case ok:
+					c.val = v
+				default:
+					c.err = errNotLoaded
+				}
+				close(c.done)
+			}
+		}()
+		values, err = fn(started)
+	}()
+	return calls
+}
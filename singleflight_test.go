@@ -0,0 +1,74 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSingleflightPanic(t *testing.T) {
+	var g singleflight[string, int]
+	started := make(chan struct{})
+	waiter := make(chan error)
+	go func() {
+		<-started
+		_, err, shared := g.Do("k", func() (int, error) { return 1, nil })
+		if !shared {
+			t.Error("expected the waiter to share the panicking call")
+		}
+		waiter <- err
+	}()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("expected the panic to reach the caller that ran fn, got %v", r)
+			}
+		}()
+		_, _, _ = g.Do("k", func() (int, error) {
+			close(started)
+			waitFor(t, func() bool { // the waiter has joined the call
+				g.mu.Lock()
+				defer g.mu.Unlock()
+				return g.calls["k"].dups == 1
+			})
+			panic("boom")
+		})
+	}()
+
+	if err := <-waiter; err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the waiter to receive the panic as an error, got %v", err)
+	}
+	if v, err, _ := g.Do("k", func() (int, error) { return 2, nil }); err != nil || v != 2 {
+		t.Fatalf("expected the key to be usable after the panic, got %d,%v", v, err)
+	}
+}
+
+func TestSingleflightShared(t *testing.T) {
+	var g singleflight[string, int]
+	if _, _, shared := g.Do("k", func() (int, error) { return 1, nil }); shared {
+		t.Fatal("expected a call without waiters not to be shared")
+	}
+
+	started := make(chan struct{})
+	waiter := make(chan bool)
+	go func() {
+		<-started
+		_, _, shared := g.Do("k", func() (int, error) { return 3, nil })
+		waiter <- shared
+	}()
+	v, _, shared := g.Do("k", func() (int, error) {
+		close(started)
+		waitFor(t, func() bool { // the waiter has joined the call
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			return g.calls["k"].dups == 1
+		})
+		return 2, nil
+	})
+	if v != 2 || !shared {
+		t.Fatalf("expected the caller that ran fn to report its result as shared, got %d, %v", v, shared)
+	}
+	if !<-waiter {
+		t.Fatal("expected the waiter to report the result as shared")
+	}
+}
@@ -0,0 +1,282 @@
+// Package util provides utility functions and types for common operations.
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrTaskCycle is returned when the dependencies of a TaskGraph form a cycle.
+var ErrTaskCycle = errors.New("task dependency cycle")
+
+// TaskOutcome describes how a task in a TaskGraph ended.
+type TaskOutcome int
+
+const (
+	TaskSucceeded TaskOutcome = iota // the task ran and returned nil
+	TaskFailed                       // the task ran and returned an error
+	TaskSkipped                      // a dependency did not succeed, so the task never ran
+	TaskCanceled                     // the run was canceled before the task started
+)
+
+// String returns the name of the outcome.
+func (o TaskOutcome) String() string {
+	switch o {
+	case TaskSucceeded:
+		return "succeeded"
+	case TaskFailed:
+		return "failed"
+	case TaskSkipped:
+		return "skipped"
+	case TaskCanceled:
+		return "canceled"
+	default:
+		return fmt.Sprintf("TaskOutcome(%d)", int(o))
+	}
+}
+
+// FailurePolicy determines how a TaskGraph reacts to a failed task.
+type FailurePolicy int
+
+const (
+	// SkipDependents skips every task that depends, directly or transitively, on the failed task and keeps running
+	// the independent ones.
+	SkipDependents FailurePolicy = iota
+	// FailFast cancels the run: the context passed to running tasks is canceled and no new task is started.
+	FailFast
+)
+
+// TaskResult reports the outcome and timing of one task.
+type TaskResult struct {
+	ID       string
+	Outcome  TaskOutcome
+	Err      error         // the task error for TaskFailed
+	Start    time.Time     // zero unless the task ran
+	Duration time.Duration // zero unless the task ran
+}
+
+// TaskReport summarizes a TaskGraph run.
+type TaskReport struct {
+	Results  []TaskResult // one per task, in the order the tasks were added
+	Duration time.Duration
+}
+
+// Count returns the number of tasks that ended with the given outcome.
+func (r *TaskReport) Count(o TaskOutcome) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Outcome == o {
+			n++
+		}
+	}
+	return n
+}
+
+// task is a node of a TaskGraph.
+type task struct {
+	id   string
+	deps []string
+	fn   func(context.Context) error
+}
+
+// TaskGraph runs tasks that depend on each other. Tasks declare their dependencies by ID; a task starts once all of its
+// dependencies have succeeded. Ready tasks run concurrently on a WorkerPool. TaskGraph is not safe for concurrent use
+// while tasks are being added.
+type TaskGraph struct {
+	tasks map[string]*task
+	order []string
+	clock Clock
+}
+
+// NewTaskGraph creates an empty TaskGraph.
+func NewTaskGraph() *TaskGraph {
+	return &TaskGraph{tasks: make(map[string]*task), clock: SystemClock}
+}
+
+// WithClock sets the Clock used for the start times and durations in the TaskReport and returns the graph for
+// chaining. It must not be called during a Run.
+func (g *TaskGraph) WithClock(clock Clock) *TaskGraph {
+	g.clock = clock
+	return g
+}
+
+// Add adds a task. Dependencies may refer to tasks that are added later.
+//
+// Parameters:
+//   - id: The unique task ID
+//   - fn: The task function; it should honor ctx cancellation
+//   - deps: The IDs of the tasks that must succeed before this one runs
+//
+// Returns:
+//   - An error if a task with the same ID already exists
+func (g *TaskGraph) Add(id string, fn func(ctx context.Context) error, deps ...string) error {
+	if _, ok := g.tasks[id]; ok {
+		return fmt.Errorf("task %q already exists", id)
+	}
+	g.tasks[id] = &task{id: id, deps: deps, fn: fn}
+	g.order = append(g.order, id)
+	return nil
+}
+
+// Validate checks that every dependency exists and that the graph has no cycles.
+//
+// Returns:
+//   - nil if the graph can run, an error naming the unknown dependency, or an error wrapping ErrTaskCycle that
+//     lists the cycle
+func (g *TaskGraph) Validate() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.tasks))
+	var path []string
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			start := 0
+			for path[start] != id {
+				start++
+			}
+			return fmt.Errorf("%w: %s -> %s", ErrTaskCycle, strings.Join(path[start:], " -> "), id)
+		case visited:
+			return nil
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range g.tasks[id].deps {
+			if _, ok := g.tasks[dep]; !ok {
+				return fmt.Errorf("task %q depends on unknown task %q", id, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		return nil
+	}
+
+	for _, id := range g.order {
+		if err := visit(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run validates the graph and runs every task, with at most numWorkers tasks running at the same time.
+//
+// Parameters:
+//   - ctx: The context passed to every task; canceling it stops the run like FailFast
+//   - numWorkers: The maximum number of concurrently running tasks
+//   - policy: How to react to a failed task
+//
+// Returns:
+//   - A report with the outcome and timing of every task, or nil if the graph is invalid
+//   - The validation error, the first task error (wrapped with its task ID), or ctx.Err(); nil if every task succeeded
+//
+// Panics:
+//   - If numWorkers is less than 1
+func (g *TaskGraph) Run(ctx context.Context, numWorkers int, policy FailurePolicy) (*TaskReport, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	clock := g.clock
+	started := clock.Now()
+	pool := NewWorkerPool(func(t *task) TaskResult {
+		res := TaskResult{ID: t.id}
+		if ctx.Err() != nil {
+			res.Outcome = TaskCanceled
+			return res
+		}
+		res.Start = clock.Now()
+		res.Err = t.fn(ctx)
+		res.Duration = clock.Now().Sub(res.Start)
+		if res.Err != nil {
+			res.Outcome = TaskFailed
+		}
+		return res
+	}, len(g.tasks), numWorkers)
+	defer pool.Close()
+
+	waiting := make(map[string]int, len(g.tasks))         // unfinished dependencies per task
+	dependents := make(map[string][]string, len(g.tasks)) // reverse edges
+	for _, id := range g.order {
+		t := g.tasks[id]
+		waiting[id] = len(t.deps)
+		for _, dep := range t.deps {
+			dependents[dep] = append(dependents[dep], id)
+		}
+	}
+
+	results := make(map[string]TaskResult, len(g.tasks))
+	var firstErr error
+	outstanding := 0
+	for _, id := range g.order {
+		if waiting[id] == 0 {
+			pool.Post(g.tasks[id])
+			outstanding++
+		}
+	}
+
+	// skip marks every transitive dependent of id as skipped
+	var skip func(id string)
+	skip = func(id string) {
+		for _, d := range dependents[id] {
+			if _, done := results[d]; !done {
+				results[d] = TaskResult{ID: d, Outcome: TaskSkipped}
+				skip(d)
+			}
+		}
+	}
+
+	for outstanding > 0 {
+		res := pool.Result()
+		outstanding--
+		results[res.ID] = res
+
+		if res.Outcome != TaskSucceeded {
+			if res.Outcome == TaskFailed && firstErr == nil {
+				firstErr = fmt.Errorf("task %q: %w", res.ID, res.Err)
+			}
+			if res.Outcome == TaskFailed && policy == FailFast {
+				cancel()
+			}
+			skip(res.ID)
+			continue
+		}
+
+		for _, d := range dependents[res.ID] {
+			waiting[d]--
+			if _, done := results[d]; !done && waiting[d] == 0 && ctx.Err() == nil {
+				pool.Post(g.tasks[d])
+				outstanding++
+			}
+		}
+	}
+
+	report := &TaskReport{Results: make([]TaskResult, 0, len(g.order)), Duration: clock.Now().Sub(started)}
+	for _, id := range g.order {
+		res, ok := results[id]
+		if !ok {
+			res = TaskResult{ID: id, Outcome: TaskCanceled}
+		}
+		report.Results = append(report.Results, res)
+	}
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	return report, firstErr
+}
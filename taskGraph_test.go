@@ -0,0 +1,141 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTaskGraphRunsInDependencyOrder(t *testing.T) {
+	var mu sync.Mutex
+	finished := map[string]bool{}
+	task := func(id string, deps ...string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, d := range deps {
+				if !finished[d] {
+					t.Errorf("task %s ran before its dependency %s", id, d)
+				}
+			}
+			finished[id] = true
+			return nil
+		}
+	}
+
+	g := NewTaskGraph()
+	_ = g.Add("link", task("link", "compileA", "compileB"), "compileA", "compileB")
+	_ = g.Add("compileA", task("compileA", "generate"), "generate")
+	_ = g.Add("compileB", task("compileB", "generate"), "generate")
+	_ = g.Add("generate", task("generate"))
+
+	report, err := g.Run(context.Background(), 2, SkipDependents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := report.Count(TaskSucceeded); n != 4 {
+		t.Fatalf("expected 4 successes, got %d", n)
+	}
+	if report.Results[0].ID != "link" || report.Results[0].Start.IsZero() {
+		t.Fatalf("expected results in insertion order with timing, got %+v", report.Results[0])
+	}
+}
+
+func TestTaskGraphDetectsCycles(t *testing.T) {
+	g := NewTaskGraph()
+	noop := func(context.Context) error { return nil }
+	_ = g.Add("a", noop, "c")
+	_ = g.Add("b", noop, "a")
+	_ = g.Add("c", noop, "b")
+
+	report, err := g.Run(context.Background(), 1, SkipDependents)
+	if !errors.Is(err, ErrTaskCycle) || report != nil {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+
+	g = NewTaskGraph()
+	_ = g.Add("a", noop, "missing")
+	if err := g.Validate(); err == nil {
+		t.Fatal("expected an unknown dependency error")
+	}
+	if err := g.Add("a", noop); err == nil {
+		t.Fatal("expected a duplicate task error")
+	}
+}
+
+func TestTaskGraphSkipDependents(t *testing.T) {
+	boom := errors.New("boom")
+	g := NewTaskGraph()
+	_ = g.Add("fail", func(context.Context) error { return boom })
+	_ = g.Add("child", func(context.Context) error { return nil }, "fail")
+	_ = g.Add("grandchild", func(context.Context) error { return nil }, "child")
+	_ = g.Add("independent", func(context.Context) error { return nil })
+
+	report, err := g.Run(context.Background(), 2, SkipDependents)
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	want := []TaskOutcome{TaskFailed, TaskSkipped, TaskSkipped, TaskSucceeded}
+	for i, res := range report.Results {
+		if res.Outcome != want[i] {
+			t.Fatalf("task %s: expected %v, got %v", res.ID, want[i], res.Outcome)
+		}
+	}
+}
+
+func TestTaskGraphFailFast(t *testing.T) {
+	boom := errors.New("boom")
+	g := NewTaskGraph()
+	_ = g.Add("fail", func(context.Context) error { return boom })
+	_ = g.Add("slow", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	_ = g.Add("later", func(context.Context) error { return nil }, "slow")
+
+	start := time.Now()
+	report, err := g.Run(context.Background(), 2, FailFast)
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("expected fail fast to cancel the running task")
+	}
+	if o := report.Results[2].Outcome; o != TaskSkipped && o != TaskCanceled {
+		t.Fatalf("expected the dependent task not to run, got %v", o)
+	}
+}
+
+func TestTaskGraphClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	g := NewTaskGraph().WithClock(clock)
+	_ = g.Add("fetch", func(context.Context) error {
+		clock.Advance(time.Second)
+		return nil
+	})
+	_ = g.Add("build", func(context.Context) error {
+		clock.Advance(2 * time.Second)
+		return nil
+	}, "fetch")
+
+	report, err := g.Run(context.Background(), 2, SkipDependents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetch, build := report.Results[0], report.Results[1]
+	if !fetch.Start.Equal(fakeEpoch) || fetch.Duration != time.Second {
+		t.Fatalf("unexpected timing of fetch: %+v", fetch)
+	}
+	if !build.Start.Equal(fakeEpoch.Add(time.Second)) || build.Duration != 2*time.Second {
+		t.Fatalf("unexpected timing of build: %+v", build)
+	}
+	if report.Duration != 3*time.Second {
+		t.Fatalf("expected the run to take 3s, got %v", report.Duration)
+	}
+}
@@ -1,16 +1,28 @@
 package util
 
 import (
+	"context"
+	"sync"
 	"sync/atomic"
 )
 
 // WorkerPool is a generic worker pool that processes work items concurrently and stores results.
 // It uses channels to dispatch work, collect results, and retains a function to process each work item.
 type WorkerPool[W any, R any] struct {
-	work   chan W
-	result chan R
-	f      func(W) R
-	count  atomic.Int32
+	work     chan workItem[W, R]
+	result   chan R
+	f        func(W) R
+	count    atomic.Int32
+	workers  sync.WaitGroup
+	limiter  RateLimiter
+	adaptive *AdaptiveLimiter
+	failed   func(R) bool
+}
+
+// workItem is a posted work item; items submitted with Submit carry the future that receives their result.
+type workItem[W any, R any] struct {
+	w      W
+	future *Future[R]
 }
 
 // NewWorkerPool creates and initializes a new WorkerPool with the given worker function, backlog size, and number of workers.
@@ -22,20 +34,71 @@ func NewWorkerPool[W any, R any](f func(W) R, backlog int, numWorkers int) *Work
 	if numWorkers < 1 {
 		panic("numWorkers must be greater than zero")
 	}
-	pool := &WorkerPool[W, R]{result: make(chan R, backlog), work: make(chan W, backlog), f: f}
+	pool := &WorkerPool[W, R]{result: make(chan R, backlog), work: make(chan workItem[W, R], backlog), f: f}
 
 	// start n workers
+	pool.workers.Add(numWorkers)
 	for i := 0; i < numWorkers; i++ {
 		go func() {
-			for w := range pool.work { // until closed
-				pool.result <- pool.f(w)
+			defer pool.workers.Done()
+			for item := range pool.work { // until closed
+				if item.future == nil {
+					pool.result <- pool.process(item.w)
+					continue
+				}
+				if !item.future.start() { // skip canceled futures
+					pool.count.Add(-1)
+					continue
+				}
+				r := pool.process(item.w)
+				pool.count.Add(-1) // before completing, so a caller holding the result sees the pool idle
+				item.future.complete(r, nil)
 			}
 		}()
 	}
+
+	// close the result channel once every worker has drained the work channel
+	go func() {
+		pool.workers.Wait()
+		close(pool.result)
+	}()
 	return pool
 }
 
+// WithRateLimiter attaches a RateLimiter that every worker waits on before processing an item, so the pool as a whole
+// never exceeds the limiter's rate. It returns the pool for chaining and must be called before the first Post.
+func (wp *WorkerPool[W, R]) WithRateLimiter(l RateLimiter) *WorkerPool[W, R] {
+	wp.limiter = l
+	return wp
+}
+
+// WithConcurrencyLimiter attaches an AdaptiveLimiter that bounds how many workers run f at the same time. The limiter
+// learns from the latency of every call and from failed, which reports whether a result represents a failure; failed
+// may be nil. The pool's numWorkers remains the upper bound. It returns the pool for chaining and must be called before
+// the first Post.
+func (wp *WorkerPool[W, R]) WithConcurrencyLimiter(l *AdaptiveLimiter, failed func(R) bool) *WorkerPool[W, R] {
+	wp.adaptive = l
+	wp.failed = failed
+	return wp
+}
+
+// process applies f to a work item, honoring any attached limiters.
+func (wp *WorkerPool[W, R]) process(w W) R {
+	if wp.limiter != nil {
+		_ = wp.limiter.Wait(context.Background())
+	}
+	if wp.adaptive == nil {
+		return wp.f(w)
+	}
+
+	token, _ := wp.adaptive.Acquire(context.Background())
+	r := wp.f(w)
+	token.Release(wp.failed != nil && wp.failed(r))
+	return r
+}
+
 // Close terminates the work channel, signaling that no more work items will be submitted to the WorkerPool.
+// Once the workers have finished the remaining items, the result channel is closed and Next reports false.
 func (wp *WorkerPool[W, R]) Close() {
 	close(wp.work)
 }
@@ -43,16 +106,36 @@ func (wp *WorkerPool[W, R]) Close() {
 // Post submits a work item to the WorkerPool for processing and increments the active work count. This will block when the channel is full.
 func (wp *WorkerPool[W, R]) Post(w W) {
 	wp.count.Add(1)
-	wp.work <- w
+	wp.work <- workItem[W, R]{w: w}
+}
+
+// Submit submits a work item whose result is delivered to the returned Future instead of the shared result channel,
+// which lets many independent callers share the pool. It increments the active work count until the item has been
+// processed or skipped because the future was canceled. This will block when the channel is full.
+func (wp *WorkerPool[W, R]) Submit(w W) *Future[R] {
+	f := newFuture[R]()
+	wp.count.Add(1)
+	wp.work <- workItem[W, R]{w: w, future: f}
+	return f
 }
 
 // Result retrieves and returns the next available result from the worker pool, decrementing the active work count.
+// After Close, once all results have been consumed, Result returns the zero value of R.
 func (wp *WorkerPool[W, R]) Result() R {
-	v := <-wp.result
-	wp.count.Add(-1)
+	v, _ := wp.Next()
 	return v
 }
 
+// Next retrieves the next available result from the worker pool, decrementing the active work count.
+// The boolean is false when the pool has been closed and every result has been consumed.
+func (wp *WorkerPool[W, R]) Next() (R, bool) {
+	v, ok := <-wp.result
+	if ok {
+		wp.count.Add(-1)
+	}
+	return v, ok
+}
+
 // Len returns the current number of active work items in the WorkerPool.
 func (wp *WorkerPool[W, R]) Len() int32 {
 	return wp.count.Load()
@@ -13,6 +13,7 @@ func TestWorkerPool(t *testing.T) {
 
 	sumA := 0
 	sumB := 0
+	posted := make(chan struct{})
 
 	go func() {
 		for i := 1; i <= 10; i++ {
@@ -20,11 +21,13 @@ func TestWorkerPool(t *testing.T) {
 			sumA += i
 		}
 		wp.Close()
+		close(posted)
 	}()
 
 	for i := 1; i <= 10; i++ {
 		sumB += wp.Result()
 	}
+	<-posted
 
 	if wp.IsActive() {
 		t.Fail()
@@ -60,3 +63,28 @@ func TestInterruptibleWorkerPool(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestWorkerPoolNextAfterClose(t *testing.T) {
+	wp := NewWorkerPool(func(a int) int { return a * 2 }, 4, 2)
+
+	for i := 1; i <= 4; i++ {
+		wp.Post(i)
+	}
+	wp.Close()
+
+	sum := 0
+	for {
+		v, ok := wp.Next()
+		if !ok {
+			break
+		}
+		sum += v
+	}
+
+	if sum != 20 {
+		t.Fatalf("expected 20, got %d", sum)
+	}
+	if wp.IsActive() {
+		t.Fatal("expected pool to be idle after draining")
+	}
+}